@@ -0,0 +1,231 @@
+package adherence
+
+import (
+	"sort"
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// Record is one reminder reduced to what adherence needs
+type Record struct {
+	MedicineID   string
+	MedicineName string
+	Due          time.Time
+	Outcome      string // one of the models.Outcome* values
+}
+
+// Counts tallies reminder outcomes. Percentages are nil while nothing is resolved yet.
+type Counts struct {
+	Total        int      `json:"total"`
+	OnTime       int      `json:"on_time"`
+	Late         int      `json:"late"`
+	Missed       int      `json:"missed"`
+	Pending      int      `json:"pending"`
+	AdherencePct *float64 `json:"adherence_pct"`
+	OnTimePct    *float64 `json:"on_time_pct"`
+}
+
+// Summary is the counts plus day streaks for a set of reminders
+type Summary struct {
+	Counts
+	// A streak is consecutive days with doses where every resolved dose was
+	// taken; days without doses neither extend nor break it
+	CurrentStreak int `json:"current_streak"`
+	LongestStreak int `json:"longest_streak"`
+}
+
+// MedicineSummary is the summary for a single medicine
+type MedicineSummary struct {
+	MedicineID string `json:"medicine_id"`
+	Name       string `json:"name"`
+	Summary
+}
+
+// Point is one bucket of a time series, keyed by the date it starts on
+type Point struct {
+	Start string `json:"start"`
+	Counts
+}
+
+// Report is the full adherence picture over a date range
+type Report struct {
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Overall   Summary           `json:"overall"`
+	Medicines []MedicineSummary `json:"medicines"`
+	Daily     []Point           `json:"daily"`
+	Weekly    []Point           `json:"weekly"`
+}
+
+func (c *Counts) add(outcome string) {
+	c.Total++
+	switch outcome {
+	case models.OutcomeOnTime:
+		c.OnTime++
+	case models.OutcomeLate:
+		c.Late++
+	case models.OutcomeMissed:
+		c.Missed++
+	default:
+		c.Pending++
+	}
+}
+
+// finish fills in the percentages once all outcomes are counted
+func (c *Counts) finish() {
+	taken := c.OnTime + c.Late
+	resolved := taken + c.Missed
+	if resolved == 0 {
+		return
+	}
+	adherence := percent(taken, resolved)
+	onTime := percent(c.OnTime, resolved)
+	c.AdherencePct = &adherence
+	c.OnTimePct = &onTime
+}
+
+// percent rounds part/whole to one decimal place
+func percent(part, whole int) float64 {
+	return float64(int(float64(part)*1000/float64(whole)+0.5)) / 10
+}
+
+// weekStart returns the Monday starting the week that contains day
+func weekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// dayKey is the calendar date of t in loc
+func dayKey(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(dateLayout)
+}
+
+// Compute builds a report for the calendar days from..to (inclusive) in loc.
+// Records outside the range are ignored.
+func Compute(records []Record, from, to time.Time, loc *time.Location) Report {
+	first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	last := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc)
+	end := last.AddDate(0, 0, 1)
+
+	report := Report{
+		From:      first.Format(dateLayout),
+		To:        last.Format(dateLayout),
+		Medicines: []MedicineSummary{},
+		Daily:     []Point{},
+		Weekly:    []Point{},
+	}
+
+	var inRange []Record
+	for _, r := range records {
+		if r.Due.Before(first) || !r.Due.Before(end) {
+			continue
+		}
+		inRange = append(inRange, r)
+	}
+	sort.Slice(inRange, func(i, j int) bool { return inRange[i].Due.Before(inRange[j].Due) })
+
+	// Overall and per-medicine summaries
+	report.Overall = summarize(inRange, loc)
+
+	byMedicine := map[string][]Record{}
+	var order []string
+	for _, r := range inRange {
+		if _, seen := byMedicine[r.MedicineID]; !seen {
+			order = append(order, r.MedicineID)
+		}
+		byMedicine[r.MedicineID] = append(byMedicine[r.MedicineID], r)
+	}
+	for _, id := range order {
+		rs := byMedicine[id]
+		report.Medicines = append(report.Medicines, MedicineSummary{
+			MedicineID: id,
+			Name:       rs[0].MedicineName,
+			Summary:    summarize(rs, loc),
+		})
+	}
+
+	// Daily and weekly series cover every bucket in range, even empty ones
+	daily := map[string]*Counts{}
+	weekly := map[string]*Counts{}
+	for day := first; day.Before(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(dateLayout)
+		daily[key] = &Counts{}
+		report.Daily = append(report.Daily, Point{Start: key})
+
+		wk := weekStart(day).Format(dateLayout)
+		if _, ok := weekly[wk]; !ok {
+			weekly[wk] = &Counts{}
+			report.Weekly = append(report.Weekly, Point{Start: wk})
+		}
+	}
+	for _, r := range inRange {
+		daily[dayKey(r.Due, loc)].add(r.Outcome)
+		day := r.Due.In(loc)
+		weekly[weekStart(time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)).Format(dateLayout)].add(r.Outcome)
+	}
+	for i := range report.Daily {
+		report.Daily[i].Counts = *daily[report.Daily[i].Start]
+		report.Daily[i].finish()
+	}
+	for i := range report.Weekly {
+		report.Weekly[i].Counts = *weekly[report.Weekly[i].Start]
+		report.Weekly[i].finish()
+	}
+
+	return report
+}
+
+// summarize counts outcomes and works out streaks; records must be sorted by Due
+func summarize(records []Record, loc *time.Location) Summary {
+	var s Summary
+
+	type day struct {
+		resolved bool
+		perfect  bool
+	}
+	var days []string
+	perDay := map[string]*day{}
+
+	for _, r := range records {
+		s.add(r.Outcome)
+
+		key := dayKey(r.Due, loc)
+		d, ok := perDay[key]
+		if !ok {
+			d = &day{perfect: true}
+			perDay[key] = d
+			days = append(days, key)
+		}
+		switch r.Outcome {
+		case models.OutcomeOnTime, models.OutcomeLate:
+			d.resolved = true
+		case models.OutcomeMissed:
+			d.resolved = true
+			d.perfect = false
+		}
+	}
+	s.finish()
+
+	run := 0
+	for _, key := range days {
+		d := perDay[key]
+		if !d.resolved {
+			// Only pending doses so far: the day does not count yet
+			continue
+		}
+		if d.perfect {
+			run++
+			if run > s.LongestStreak {
+				s.LongestStreak = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	s.CurrentStreak = run
+
+	return s
+}
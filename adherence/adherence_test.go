@@ -0,0 +1,177 @@
+package adherence
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"pillTickr-backend/models"
+)
+
+// at returns 08:00 in loc on the given day of June 2025; June 1 is a Sunday
+func at(day int, loc *time.Location) time.Time {
+	return time.Date(2025, time.June, day, 8, 0, 0, 0, loc)
+}
+
+func record(day int, outcome string) Record {
+	return Record{MedicineID: "1", MedicineName: "Aspirin", Due: at(day, time.UTC), Outcome: outcome}
+}
+
+func pct(p *float64) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func TestComputeOnTimeAndLate(t *testing.T) {
+	records := []Record{
+		record(2, models.OutcomeOnTime),
+		record(3, models.OutcomeOnTime),
+		record(4, models.OutcomeLate),
+		record(5, models.OutcomeMissed),
+		record(6, models.OutcomePending),
+	}
+	r := Compute(records, at(2, time.UTC), at(6, time.UTC), time.UTC)
+
+	c := r.Overall.Counts
+	if c.Total != 5 || c.OnTime != 2 || c.Late != 1 || c.Missed != 1 || c.Pending != 1 {
+		t.Fatalf("counts = %+v", c)
+	}
+	// Pending doses are left out of both percentages; late ones count as taken
+	if got := pct(c.AdherencePct); got != 75.0 {
+		t.Errorf("adherence_pct = %v, want 75", got)
+	}
+	if got := pct(c.OnTimePct); got != 50.0 {
+		t.Errorf("on_time_pct = %v, want 50", got)
+	}
+	if got := pct(r.Daily[4].AdherencePct); got != nil {
+		t.Errorf("adherence_pct of a day with only pending doses = %v, want nil", got)
+	}
+}
+
+func TestComputeStreaks(t *testing.T) {
+	tests := []struct {
+		name             string
+		records          []Record
+		current, longest int
+	}{
+		{
+			name: "late doses keep the streak",
+			records: []Record{
+				record(2, models.OutcomeOnTime),
+				record(3, models.OutcomeLate),
+				record(4, models.OutcomeOnTime),
+			},
+			current: 3, longest: 3,
+		},
+		{
+			// Reminders inside a pause are left out of the history, so those
+			// days have no records and neither extend nor break the streak
+			name: "paused days are skipped",
+			records: []Record{
+				record(2, models.OutcomeOnTime),
+				record(3, models.OutcomeOnTime),
+				record(9, models.OutcomeOnTime),
+			},
+			current: 3, longest: 3,
+		},
+		{
+			name: "a missed Monday breaks a streak across the week boundary",
+			records: []Record{
+				record(6, models.OutcomeOnTime), // Friday
+				record(7, models.OutcomeOnTime),
+				record(8, models.OutcomeOnTime), // Sunday
+				record(9, models.OutcomeMissed), // Monday
+				record(10, models.OutcomeOnTime),
+			},
+			current: 1, longest: 3,
+		},
+		{
+			name: "a streak runs on across the week boundary",
+			records: []Record{
+				record(7, models.OutcomeOnTime),
+				record(8, models.OutcomeOnTime), // Sunday
+				record(9, models.OutcomeOnTime), // Monday
+				record(10, models.OutcomeOnTime),
+			},
+			current: 4, longest: 4,
+		},
+		{
+			name: "one missed dose spoils the day",
+			records: []Record{
+				record(2, models.OutcomeOnTime),
+				record(3, models.OutcomeOnTime),
+				{MedicineID: "1", Due: at(3, time.UTC).Add(12 * time.Hour), Outcome: models.OutcomeMissed},
+				record(4, models.OutcomeOnTime),
+			},
+			current: 1, longest: 1,
+		},
+		{
+			name: "pending doses do not count yet",
+			records: []Record{
+				record(2, models.OutcomeOnTime),
+				record(3, models.OutcomePending),
+			},
+			current: 1, longest: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Compute(tt.records, at(1, time.UTC), at(30, time.UTC), time.UTC)
+			if r.Overall.CurrentStreak != tt.current || r.Overall.LongestStreak != tt.longest {
+				t.Errorf("streaks = %d current, %d longest; want %d, %d",
+					r.Overall.CurrentStreak, r.Overall.LongestStreak, tt.current, tt.longest)
+			}
+		})
+	}
+}
+
+func TestComputeBuckets(t *testing.T) {
+	records := []Record{
+		record(7, models.OutcomeOnTime),
+		record(8, models.OutcomeMissed), // Sunday
+		record(9, models.OutcomeOnTime), // Monday
+		record(20, models.OutcomeOnTime),
+	}
+	r := Compute(records, at(7, time.UTC), at(10, time.UTC), time.UTC)
+
+	if r.From != "2025-06-07" || r.To != "2025-06-10" {
+		t.Errorf("range = %s..%s", r.From, r.To)
+	}
+	if r.Overall.Total != 3 {
+		t.Errorf("total = %d, want 3 with the record outside the range ignored", r.Overall.Total)
+	}
+	if len(r.Daily) != 4 || r.Daily[3].Total != 0 {
+		t.Errorf("daily = %+v, want 4 days with an empty last one", r.Daily)
+	}
+
+	// Weeks start on Monday, so Saturday and Sunday fall in the earlier one
+	if len(r.Weekly) != 2 {
+		t.Fatalf("got %d weeks, want 2", len(r.Weekly))
+	}
+	if w := r.Weekly[0]; w.Start != "2025-06-02" || w.Total != 2 || w.Missed != 1 {
+		t.Errorf("first week = %+v", w)
+	}
+	if w := r.Weekly[1]; w.Start != "2025-06-09" || w.Total != 1 || w.OnTime != 1 {
+		t.Errorf("second week = %+v", w)
+	}
+}
+
+func TestComputeDaysInUserZone(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 23:30 UTC on Sunday is already Monday in Tokyo
+	records := []Record{
+		{MedicineID: "1", Due: time.Date(2025, 6, 8, 23, 30, 0, 0, time.UTC), Outcome: models.OutcomeOnTime},
+	}
+	r := Compute(records, at(8, loc), at(9, loc), loc)
+	if r.Daily[0].Total != 0 || r.Daily[1].Total != 1 {
+		t.Errorf("daily = %+v, want the dose on 2025-06-09", r.Daily)
+	}
+	if r.Weekly[len(r.Weekly)-1].Start != "2025-06-09" || r.Weekly[len(r.Weekly)-1].Total != 1 {
+		t.Errorf("weekly = %+v, want the dose in the week of 2025-06-09", r.Weekly)
+	}
+}
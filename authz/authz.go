@@ -0,0 +1,38 @@
+package authz
+
+import (
+	"context"
+	"errors"
+)
+
+// Resource is a kind of user-owned record that can be addressed by ID
+type Resource string
+
+const (
+	Medicine     Resource = "medicine"
+	Schedule     Resource = "schedule"
+	ScheduleTime Resource = "schedule_time"
+	Reminder     Resource = "reminder"
+)
+
+// ErrNotFound is returned both for missing records and for records owned by
+// someone else, so callers cannot probe for other users' IDs
+var ErrNotFound = errors.New("resource not found")
+
+// OwnerResolver looks up the user owning a resource. It returns ErrNotFound
+// when the resource does not exist.
+type OwnerResolver interface {
+	OwnerOf(ctx context.Context, resource Resource, id string) (string, error)
+}
+
+// Authorize returns nil if userID owns the resource and ErrNotFound otherwise
+func Authorize(ctx context.Context, owners OwnerResolver, userID string, resource Resource, id string) error {
+	owner, err := owners.OwnerOf(ctx, resource, id)
+	if err != nil {
+		return err
+	}
+	if owner != userID {
+		return ErrNotFound
+	}
+	return nil
+}
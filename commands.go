@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"pillTickr-backend/crypto"
+	"pillTickr-backend/db"
+	"pillTickr-backend/keyrotation"
+	"pillTickr-backend/store"
+)
+
+const usage = `Usage:
+  pilltickr                      start the API server
+  pilltickr migrate up           apply all pending migrations
+  pilltickr migrate down [N]     revert the last N migrations (default 1)
+  pilltickr migrate status       list migrations and whether they are applied
+  pilltickr reencrypt            encrypt plaintext rows and move encrypted rows to the active key
+  pilltickr encrypt-medicines    same as reencrypt
+  pilltickr erase-user ID        delete a user and destroy their data key (erasure request);
+                                 name and email in backups are not encrypted`
+
+// runCommand executes a CLI subcommand and returns the process exit code
+func runCommand(args []string) int {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(args[1:])
+	case "reencrypt", "encrypt-medicines":
+		return runReencrypt()
+	case "erase-user":
+		return runEraseUser(args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", args[0], usage)
+		return 2
+	}
+}
+
+// runMigrate handles "migrate up|down|status"
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	if err := db.Open(databaseSource()); err != nil {
+		return 1
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(db.DB, db.Current)
+		if err != nil {
+			slog.Error("Migrate up failed", "error", err)
+			return 1
+		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "invalid step count %q\n", args[1])
+				return 2
+			}
+			steps = n
+		}
+		reverted, err := db.MigrateDown(db.DB, db.Current, steps)
+		if err != nil {
+			slog.Error("Migrate down failed", "error", err)
+			return 1
+		}
+		fmt.Printf("Reverted %d migration(s)\n", reverted)
+
+	case "status":
+		statuses, err := db.Status(db.DB, db.Current)
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, state)
+		}
+		if err != nil {
+			slog.Error("Migrate status failed", "error", err)
+			return 1
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n\n%s\n", args[0], usage)
+		return 2
+	}
+	return 0
+}
+
+// runReencrypt re-wraps user data keys still under a retired master key, and
+// encrypts with the owner's data key, in place, medicine fields that are
+// plaintext or still under a master key. Rows already current are left alone,
+// so it is safe to run more than once; once it reports nothing left, retired
+// keys can be removed.
+func runReencrypt() int {
+	if err := loadKeyring(); err != nil {
+		return 1
+	}
+	// The schema must be current: Postgres needs its columns widened for ciphertext
+	if err := db.Init(databaseSource()); err != nil {
+		return 1
+	}
+	defer db.Close()
+
+	st := store.New(db.DB, db.Current)
+	changed, err := keyrotation.NewReencryptor(st, 0).ReencryptAll(context.Background())
+	if err != nil {
+		slog.Error("Re-encryption failed", "re_encrypted", changed, "error", err)
+		return 1
+	}
+	fmt.Printf("Re-encrypted %d row(s) with key %q\n", changed, crypto.ActiveKeyID())
+	return 0
+}
+
+// runEraseUser honours an erasure request: the user's rows are deleted and
+// their data key destroyed, which leaves the encrypted medicine fields in
+// backups unreadable. Name, email and schedule timing are not encrypted, so
+// backups keep identifying the user until they expire.
+func runEraseUser(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if err := loadKeyring(); err != nil {
+		return 1
+	}
+	if err := db.Init(databaseSource()); err != nil {
+		return 1
+	}
+	defer db.Close()
+
+	err := store.New(db.DB, db.Current).Users().DeleteUser(context.Background(), args[0])
+	if errors.Is(err, store.ErrNotFound) {
+		fmt.Fprintf(os.Stderr, "user %q not found\n", args[0])
+		return 1
+	}
+	if err != nil {
+		slog.Error("Erasing user failed", "user_id", args[0], "error", err)
+		return 1
+	}
+	fmt.Printf("Erased user %s and destroyed their data key\n", args[0])
+	fmt.Println("Backups still hold the user's name and email in plaintext until they expire")
+	return 0
+}
@@ -8,33 +8,55 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"strings"
 )
 
 var (
-	encryptionKey []byte
+	keyring       *Keyring
 	ErrKeyNotSet  = errors.New("encryption key not set")
 	ErrInvalidKey = errors.New("invalid encryption key")
 )
 
-// SetKey sets the encryption key with validation
+// Ciphertext is "<key id>:" followed by base64(nonce||ciphertext), so it can
+// be decrypted after the active key has been rotated. Ciphertext written
+// before key IDs existed has no prefix and is tried against every key.
+
+// SetKeyring installs the keys used by Encrypt and Decrypt
+func SetKeyring(k *Keyring) {
+	keyring = k
+	slog.Info("Encryption keyring set successfully", "active_key_id", k.ActiveID(), "keys", len(k.keys))
+}
+
+// SetKey sets a single encryption key, named DefaultKeyID, with validation
 func SetKey(key []byte) error {
-	if len(key) != 32 {
-		slog.Error("Invalid encryption key length", "expected", 32, "got", len(key))
+	k, err := NewKeyring(DefaultKeyID, key)
+	if err != nil {
+		slog.Error("Invalid encryption key", "error", err)
 		return ErrInvalidKey
 	}
-	encryptionKey = key
-	slog.Info("Encryption key set successfully")
+	SetKeyring(k)
 	return nil
 }
 
-// GetKey returns the current encryption key (for testing purposes)
+// GetKey returns the active encryption key (for testing purposes)
 func GetKey() []byte {
-	return encryptionKey
+	if keyring == nil {
+		return nil
+	}
+	return keyring.activeKey()
 }
 
-// Encrypt encrypts a plaintext string with proper error handling
+// ActiveKeyID returns the ID of the key new ciphertext is written with
+func ActiveKeyID() string {
+	if keyring == nil {
+		return ""
+	}
+	return keyring.ActiveID()
+}
+
+// Encrypt encrypts a plaintext string with the active key
 func Encrypt(plain string) (string, error) {
-	if len(encryptionKey) == 0 {
+	if keyring == nil {
 		slog.Error("Encryption attempted without key")
 		return "", ErrKeyNotSet
 	}
@@ -43,34 +65,20 @@ func Encrypt(plain string) (string, error) {
 		return "", nil
 	}
 
-	block, err := aes.NewCipher(encryptionKey)
-	if err != nil {
-		slog.Error("Failed to create cipher", "error", err)
-		return "", err
-	}
-
-	aesgcm, err := cipher.NewGCM(block)
+	ciphertext, err := seal(keyring.activeKey(), []byte(plain))
 	if err != nil {
-		slog.Error("Failed to create GCM", "error", err)
-		return "", err
-	}
-
-	nonce := make([]byte, aesgcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		slog.Error("Failed to generate nonce", "error", err)
+		slog.Error("Failed to encrypt", "error", err)
 		return "", err
 	}
-
-	ciphertext := aesgcm.Seal(nonce, nonce, []byte(plain), nil)
-	encoded := base64.StdEncoding.EncodeToString(ciphertext)
+	encoded := keyring.ActiveID() + ":" + base64.StdEncoding.EncodeToString(ciphertext)
 
 	slog.Debug("Text encrypted successfully", "length", len(plain))
 	return encoded, nil
 }
 
-// Decrypt decrypts an encrypted string with proper error handling
+// Decrypt decrypts a string produced by Encrypt with whichever key it names
 func Decrypt(encoded string) (string, error) {
-	if len(encryptionKey) == 0 {
+	if keyring == nil {
 		slog.Error("Decryption attempted without key")
 		return "", ErrKeyNotSet
 	}
@@ -79,39 +87,75 @@ func Decrypt(encoded string) (string, error) {
 		return "", nil
 	}
 
+	keys := keyring.candidates()
+	if id, rest, found := strings.Cut(encoded, ":"); found {
+		key, err := keyring.lookup(id)
+		if err != nil {
+			slog.Error("Failed to decrypt", "error", err)
+			return "", err
+		}
+		keys = [][]byte{key}
+		encoded = rest
+	}
+
 	data, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		slog.Error("Failed to decode base64", "error", err)
 		return "", err
 	}
 
-	block, err := aes.NewCipher(encryptionKey)
+	for _, key := range keys {
+		plain, err := open(key, data)
+		if err == nil {
+			slog.Debug("Text decrypted successfully", "length", len(plain))
+			return string(plain), nil
+		}
+		if len(keys) == 1 {
+			slog.Error("Failed to decrypt", "error", err)
+			return "", err
+		}
+	}
+	slog.Error("Failed to decrypt with any known key", "keys", len(keys))
+	return "", ErrUnknownKey
+}
+
+// newGCM creates the AES-GCM cipher for a key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		slog.Error("Failed to create cipher for decryption", "error", err)
-		return "", err
+		return nil, err
 	}
+	return cipher.NewGCM(block)
+}
 
-	aesgcm, err := cipher.NewGCM(block)
+// seal encrypts plain with key under a fresh random nonce and returns nonce||ciphertext
+func seal(key, plain []byte) ([]byte, error) {
+	aesgcm, err := newGCM(key)
 	if err != nil {
-		slog.Error("Failed to create GCM for decryption", "error", err)
-		return "", err
+		return nil, err
 	}
 
-	nonceSize := aesgcm.NonceSize()
-	if len(data) < nonceSize {
-		slog.Error("Ciphertext too short", "length", len(data), "expected_min", nonceSize)
-		return "", errors.New("ciphertext too short")
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
 	}
+	return aesgcm.Seal(nonce, nonce, plain, nil), nil
+}
 
-	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plain, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+// open decrypts nonce||ciphertext with key
+func open(key, data []byte) ([]byte, error) {
+	aesgcm, err := newGCM(key)
 	if err != nil {
-		slog.Error("Failed to decrypt", "error", err)
-		return "", err
+		return nil, err
 	}
 
-	slog.Debug("Text decrypted successfully", "length", len(plain))
-	return string(plain), nil
+	nonceSize := aesgcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	return aesgcm.Open(nil, nonce, ciphertext, nil)
 }
 
 // SafeEncrypt encrypts text and returns empty string on error (for backward compatibility)
@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Each user's data is encrypted with their own data-encryption key (DEK).
+// DEKs are only ever stored wrapped (encrypted) by the master keyring, so
+// destroying a user's wrapped DEK makes everything encrypted with it
+// unreadable, wherever copies of it live.
+
+// dataKeyID marks field values encrypted with a DEK rather than a master key;
+// the keyring refuses it as a key ID so the two can never be confused
+const dataKeyID = "dek"
+
+const dataKeySize = 32
+
+// ErrNoDataKey means a value is encrypted with a data key that is not
+// available, normally because the user's key was destroyed
+var ErrNoDataKey = errors.New("data encryption key not available")
+
+// NewDataKey generates a random data-encryption key
+func NewDataKey() ([]byte, error) {
+	dek := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+	return dek, nil
+}
+
+// WrapKey encrypts a data key with the active master key for storage
+func WrapKey(dek []byte) (string, error) {
+	return Encrypt(base64.StdEncoding.EncodeToString(dek))
+}
+
+// UnwrapKey reverses WrapKey, using whichever master key wrapped it
+func UnwrapKey(wrapped string) ([]byte, error) {
+	encoded, err := Decrypt(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(dek) != dataKeySize {
+		return nil, ErrInvalidKey
+	}
+	return dek, nil
+}
+
+// IsWrappedWithActiveKey reports whether a wrapped key uses the active master key
+func IsWrappedWithActiveKey(wrapped string) bool {
+	return strings.HasPrefix(wrapped, ActiveKeyID()+":")
+}
+
+// DataFieldPrefix is how values written by EncryptDataField begin
+func DataFieldPrefix() string {
+	return fieldPrefix + dataKeyID + ":"
+}
+
+// EncryptDataField encrypts a value for storage with a user's data key.
+// Empty values are stored as they are.
+func EncryptDataField(dek []byte, plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	ciphertext, err := seal(dek, []byte(plain))
+	if err != nil {
+		return "", err
+	}
+	return DataFieldPrefix() + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptDataField decrypts a value written by EncryptDataField with dek.
+// Values written under a master key, or before encryption was enabled, are
+// handed to DecryptField so rows not yet re-encrypted stay readable.
+func DecryptDataField(dek []byte, stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, DataFieldPrefix())
+	if !ok {
+		return DecryptField(stored)
+	}
+	if dek == nil {
+		return "", ErrNoDataKey
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(dek, data)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,34 @@
+package crypto
+
+import "strings"
+
+// fieldPrefix marks column values written by EncryptField, so rows stored
+// before encryption was enabled can be recognised and still read
+const fieldPrefix = "enc:"
+
+// IsEncryptedField reports whether a stored column value was written by EncryptField
+func IsEncryptedField(stored string) bool {
+	return strings.HasPrefix(stored, fieldPrefix)
+}
+
+// EncryptField encrypts a value for storage in a database column. Empty
+// values are stored as they are.
+func EncryptField(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	encrypted, err := Encrypt(plain)
+	if err != nil {
+		return "", err
+	}
+	return fieldPrefix + encrypted, nil
+}
+
+// DecryptField reverses EncryptField. Values without the prefix are legacy
+// plaintext and are returned unchanged.
+func DecryptField(stored string) (string, error) {
+	if !IsEncryptedField(stored) {
+		return stored, nil
+	}
+	return Decrypt(strings.TrimPrefix(stored, fieldPrefix))
+}
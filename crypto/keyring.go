@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultKeyID names ENCRYPTION_KEY when ENCRYPTION_KEY_ID is not set
+const DefaultKeyID = "1"
+
+var (
+	ErrUnknownKey   = errors.New("ciphertext uses an unknown encryption key")
+	ErrInvalidKeyID = errors.New("invalid encryption key id")
+)
+
+// keyIDPattern keeps IDs free of the ':' that separates them from the
+// ciphertext; dataKeyID is reserved on top of it
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9.-]{1,32}$`)
+
+// Keyring holds the active key, used for all new ciphertext, and retired keys
+// that are only used to decrypt data written before a rotation
+type Keyring struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+// NewKeyring creates a keyring whose active key is key, named id
+func NewKeyring(id string, key []byte) (*Keyring, error) {
+	k := &Keyring{keys: map[string][]byte{}}
+	if err := k.add(id, key); err != nil {
+		return nil, err
+	}
+	k.activeID = id
+	return k, nil
+}
+
+// AddRetired adds a decrypt-only key
+func (k *Keyring) AddRetired(id string, key []byte) error {
+	return k.add(id, key)
+}
+
+func (k *Keyring) add(id string, key []byte) error {
+	if !keyIDPattern.MatchString(id) || id == dataKeyID {
+		return fmt.Errorf("%w %q: use up to 32 letters, digits, '.' or '-'", ErrInvalidKeyID, id)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("%w: key %q must be 32 bytes, got %d", ErrInvalidKey, id, len(key))
+	}
+	if _, exists := k.keys[id]; exists {
+		return fmt.Errorf("%w: key %q given twice", ErrInvalidKeyID, id)
+	}
+	k.keys[id] = key
+	return nil
+}
+
+// ActiveID returns the ID of the key new ciphertext is written with
+func (k *Keyring) ActiveID() string {
+	return k.activeID
+}
+
+func (k *Keyring) activeKey() []byte {
+	return k.keys[k.activeID]
+}
+
+// lookup returns the key with the given ID
+func (k *Keyring) lookup(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// candidates returns every key, active first, for ciphertext that predates key IDs
+func (k *Keyring) candidates() [][]byte {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		if id != k.activeID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	keys := [][]byte{k.activeKey()}
+	for _, id := range ids {
+		keys = append(keys, k.keys[id])
+	}
+	return keys
+}
+
+// KeyringFromEnv builds the keyring from ENCRYPTION_KEY (the active key),
+// ENCRYPTION_KEY_ID (its ID, default "1") and ENCRYPTION_RETIRED_KEYS, a
+// comma-separated list of id:key pairs still needed to read older data.
+func KeyringFromEnv() (*Keyring, error) {
+	key := os.Getenv("ENCRYPTION_KEY")
+	if key == "" {
+		return nil, errors.New("ENCRYPTION_KEY environment variable is required")
+	}
+	id := os.Getenv("ENCRYPTION_KEY_ID")
+	if id == "" {
+		id = DefaultKeyID
+	}
+
+	keyring, err := NewKeyring(id, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range strings.Split(os.Getenv("ENCRYPTION_RETIRED_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		retiredID, retiredKey, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("ENCRYPTION_RETIRED_KEYS: expected id:key, got an entry without ':'")
+		}
+		if err := keyring.AddRetired(retiredID, []byte(retiredKey)); err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_RETIRED_KEYS: %w", err)
+		}
+	}
+	return keyring, nil
+}
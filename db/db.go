@@ -1,53 +1,97 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"log/slog"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
 	_ "modernc.org/sqlite"
 )
 
 var DB *sql.DB
 
-// Init initializes the database connection with proper error handling
-func Init(path string) error {
-	var err error
-	DB, err = sql.Open("sqlite", path)
-	if err != nil {
-		slog.Error("Failed to open database", "path", path, "error", err)
+// Current is the dialect of DB, set by Open
+var Current Dialect
+
+// Init opens the database and applies any pending schema migrations
+func Init(source string) error {
+	if err := Open(source); err != nil {
 		return err
 	}
 
-	if err = DB.Ping(); err != nil {
-		slog.Error("Database connection failed", "path", path, "error", err)
+	applied, err := MigrateUp(DB, Current)
+	if err != nil {
+		slog.Error("Failed to migrate database", "dialect", Current, "error", err)
 		return err
 	}
 
-	// Enable foreign key constraints
-	_, err = DB.Exec(`PRAGMA foreign_keys = ON;`)
+	slog.Info("Database connected successfully", "dialect", Current, "migrations_applied", applied)
+	return nil
+}
+
+// Open connects to the database named by source without touching the schema.
+// source is a postgres:// URL or a SQLite file path, see ParseSource.
+func Open(source string) error {
+	dialect, dsn, err := ParseSource(source)
 	if err != nil {
-		slog.Error("Failed to enable foreign keys", "error", err)
+		slog.Error("Invalid database source", "error", err)
 		return err
 	}
 
-	// Set WAL mode for better concurrency
-	_, err = DB.Exec(`PRAGMA journal_mode = WAL;`)
+	switch dialect {
+	case Postgres:
+		DB, err = openPostgres(dsn)
+	default:
+		DB, err = openSQLite(dsn)
+	}
 	if err != nil {
-		slog.Error("Failed to set WAL mode", "error", err)
+		slog.Error("Failed to open database", "dialect", dialect, "error", err)
 		return err
 	}
+	Current = dialect
 
-	// Set synchronous mode for better performance vs safety trade-off
-	_, err = DB.Exec(`PRAGMA synchronous = NORMAL;`)
-	if err != nil {
-		slog.Error("Failed to set synchronous mode", "error", err)
+	if err = DB.Ping(); err != nil {
+		slog.Error("Database connection failed", "dialect", dialect, "error", err)
 		return err
 	}
 
-	slog.Info("Database connected successfully", "path", path)
 	return nil
 }
 
+// openSQLite opens the SQLite file at path
+func openSQLite(path string) (*sql.DB, error) {
+	// Pragmas go in the DSN so every pooled connection gets them, times are
+	// written in SQLite's own format so they sort and compare as text, and
+	// transactions take the write lock up front so background workers and
+	// handlers wait for each other instead of failing with SQLITE_BUSY
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_time_format=sqlite&_txlock=immediate"
+	return sql.Open("sqlite", dsn)
+}
+
+// openPostgres opens a Postgres database from a connection URL
+func openPostgres(url string) (*sql.DB, error) {
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Timestamps come back in UTC, as they do from SQLite, so API output does
+	// not depend on the server's local zone
+	utcTimestamps := stdlib.OptionAfterConnect(func(ctx context.Context, conn *pgx.Conn) error {
+		conn.TypeMap().RegisterType(&pgtype.Type{
+			Name:  "timestamptz",
+			OID:   pgtype.TimestamptzOID,
+			Codec: &pgtype.TimestamptzCodec{ScanLocation: time.UTC},
+		})
+		return nil
+	})
+	return stdlib.OpenDB(*config, utcTimestamps), nil
+}
+
 // Close closes the database connection
 func Close() error {
 	if DB != nil {
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect identifies the SQL database behind DB
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// ParseSource works out which database a source names. postgres:// and
+// postgresql:// URLs select Postgres; a sqlite: URL or a plain file path
+// selects SQLite. It returns the dialect and what to hand to Open.
+func ParseSource(source string) (Dialect, string, error) {
+	scheme, rest, hasScheme := strings.Cut(source, ":")
+	if !hasScheme || strings.ContainsAny(scheme, `/\.`) || len(scheme) == 1 {
+		// A plain path (a single letter before ':' is a Windows drive)
+		return SQLite, source, nil
+	}
+
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return Postgres, source, nil
+	case "sqlite", "sqlite3", "file":
+		path := strings.TrimPrefix(rest, "//")
+		if path == "" {
+			return "", "", fmt.Errorf("database source %q has no file path", source)
+		}
+		return SQLite, path, nil
+	default:
+		return "", "", fmt.Errorf("unsupported database scheme %q", scheme)
+	}
+}
+
+// Rebind rewrites the ? placeholders queries are written with into the
+// dialect's own form. Question marks inside quoted literals are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
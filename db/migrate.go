@@ -0,0 +1,318 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+var (
+	ErrSchemaTooNew   = errors.New("database schema is newer than this binary")
+	ErrNoMigrationSQL = errors.New("migration has no SQL for this direction")
+)
+
+// Migration is a single numbered schema change with its up and down SQL
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether a known migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// loadMigrations reads the embedded migration files for a dialect, named
+// migrations/<dialect>/NNNN_description.up.sql / .down.sql, ordered by version.
+// Every dialect carries the same versions so schema_migrations means the same
+// thing on each.
+func loadMigrations(dialect Dialect) ([]Migration, error) {
+	dir := path.Join("migrations", string(dialect))
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %q: expected NNNN_name.%s.sql", name, direction)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q: invalid version: %w", name, err)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration version %d used by both %q and %q", version, m.Name, label)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %04d_%s: %w (up)", m.Version, m.Name, ErrNoMigrationSQL)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// ensureMigrationsTable creates the bookkeeping table if it does not exist
+func ensureMigrationsTable(conn *sql.DB) error {
+	_, err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	return err
+}
+
+// appliedVersions returns the applied migration versions and when they were applied
+func appliedVersions(conn *sql.DB) (map[int]time.Time, error) {
+	rows, err := conn.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// checkNotTooNew refuses to work on a database migrated by a newer binary
+func checkNotTooNew(migrations []Migration, applied map[int]time.Time) error {
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: database is at version %d, binary knows up to %d", ErrSchemaTooNew, version, latest)
+		}
+	}
+	return nil
+}
+
+// prepareMigrations loads the embedded migrations and the applied state of conn
+func prepareMigrations(conn *sql.DB, dialect Dialect) ([]Migration, map[int]time.Time, error) {
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ensureMigrationsTable(conn); err != nil {
+		return nil, nil, err
+	}
+	applied, err := appliedVersions(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkNotTooNew(migrations, applied); err != nil {
+		return nil, nil, err
+	}
+	return migrations, applied, nil
+}
+
+// MigrateUp applies every pending migration in version order and returns how many ran
+func MigrateUp(conn *sql.DB, dialect Dialect) (int, error) {
+	migrations, applied, err := prepareMigrations(conn, dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := runInTx(conn, dialect, m.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(dialect.Rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+				m.Version, m.Name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			slog.Error("Migration failed", "version", m.Version, "name", m.Name, "error", err)
+			return count, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		slog.Info("Migration applied", "version", m.Version, "name", m.Name)
+		count++
+	}
+	return count, nil
+}
+
+// MigrateDown reverts the most recently applied migrations, at most steps of them
+func MigrateDown(conn *sql.DB, dialect Dialect, steps int) (int, error) {
+	migrations, applied, err := prepareMigrations(conn, dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if strings.TrimSpace(m.Down) == "" {
+			return count, fmt.Errorf("migration %04d_%s: %w (down)", m.Version, m.Name, ErrNoMigrationSQL)
+		}
+		err := runInTx(conn, dialect, m.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(dialect.Rebind(`DELETE FROM schema_migrations WHERE version = ?`), m.Version)
+			return err
+		})
+		if err != nil {
+			slog.Error("Migration rollback failed", "version", m.Version, "name", m.Name, "error", err)
+			return count, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		slog.Info("Migration reverted", "version", m.Version, "name", m.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func Status(conn *sql.DB, dialect Dialect) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(conn); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &at
+		}
+		statuses = append(statuses, s)
+	}
+	// Report the problem alongside the list so operators can see what is applied
+	return statuses, checkNotTooNew(migrations, applied)
+}
+
+// noForeignKeysDirective, on the first line of a SQLite migration, runs it
+// with foreign key enforcement off. SQLite needs that to rebuild a table other
+// tables reference, as dropping the old table would otherwise cascade to them.
+const noForeignKeysDirective = "-- migrate:no-foreign-keys"
+
+// runInTx executes a migration script and its bookkeeping in one transaction
+func runInTx(conn *sql.DB, dialect Dialect, script string, record func(tx *sql.Tx) error) error {
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// The pragma is a no-op inside a transaction, so it is set on the
+	// connection around it, and restored before the connection is reused
+	noForeignKeys := dialect == SQLite && strings.HasPrefix(script, noForeignKeysDirective)
+	if noForeignKeys {
+		if _, err := c.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return err
+		}
+		defer c.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+	}
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if noForeignKeys {
+		if err := checkForeignKeys(tx); err != nil {
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkForeignKeys fails if a migration run without enforcement left rows
+// pointing at missing parents
+func checkForeignKeys(tx *sql.Tx) error {
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var table string
+		var rowID sql.NullInt64
+		var parent string
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key violation: %s row %d references a missing %s row", table, rowID.Int64, parent)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS reminders;
+DROP TABLE IF EXISTS schedule_times;
+DROP TABLE IF EXISTS schedules;
+DROP TABLE IF EXISTS medicines;
+DROP TABLE IF EXISTS users;
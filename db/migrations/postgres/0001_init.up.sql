@@ -0,0 +1,51 @@
+-- Baseline schema, the Postgres counterpart of sqlite/0001_init.up.sql.
+
+CREATE TABLE IF NOT EXISTS users (
+    user_id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    email VARCHAR(150) UNIQUE NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+
+CREATE TABLE IF NOT EXISTS medicines (
+    medicine_id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    dosage VARCHAR(50),         -- e.g. "1 pill", "5ml"
+    instructions TEXT,          -- e.g. "After meals"
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS schedules (
+    schedule_id BIGSERIAL PRIMARY KEY,
+    medicine_id BIGINT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,                       -- NULL = ongoing
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily','weekly','custom')),
+    times_per_day INTEGER NOT NULL,          -- e.g. 3 times a day
+    FOREIGN KEY (medicine_id) REFERENCES medicines(medicine_id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS schedule_times (
+    time_id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL,
+    intake_time VARCHAR(5) NOT NULL,     -- "HH:MM" text as in SQLite, e.g. "08:00", "14:00"
+    FOREIGN KEY (schedule_id) REFERENCES schedules(schedule_id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS reminders (
+    reminder_id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL,
+    reminder_datetime TIMESTAMPTZ NOT NULL,
+    status TEXT CHECK (status IN ('taken', 'pending', 'missed')) DEFAULT 'pending',
+    taken_at TIMESTAMPTZ,
+    FOREIGN KEY (schedule_id) REFERENCES schedules(schedule_id) ON DELETE CASCADE
+);
+
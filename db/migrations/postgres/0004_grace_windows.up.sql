@@ -0,0 +1,4 @@
+-- How long after reminder_datetime a dose still counts as on time. Once it
+-- has passed, pending reminders are swept to 'missed' and doses taken after
+-- it count as late.
+ALTER TABLE schedules ADD COLUMN grace_minutes INTEGER NOT NULL DEFAULT 120 CHECK (grace_minutes >= 0);
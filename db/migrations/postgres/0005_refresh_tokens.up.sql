@@ -0,0 +1,18 @@
+-- Refresh tokens are stored hashed. Every login starts a token family; each
+-- refresh marks the presented token used and issues the next one in the same
+-- family, so presenting a used token again reveals a replay and revokes the
+-- whole family.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,                 -- set when rotated
+    revoked_at TIMESTAMPTZ,              -- set on logout or reuse detection
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens (user_id);
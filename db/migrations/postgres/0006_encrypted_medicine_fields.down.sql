@@ -0,0 +1,3 @@
+-- Fails if encrypted values no longer fit; decrypt the rows before reverting.
+ALTER TABLE medicines ALTER COLUMN dosage TYPE VARCHAR(50);
+ALTER TABLE medicines ALTER COLUMN name TYPE VARCHAR(100);
@@ -0,0 +1,4 @@
+-- Medicine fields are stored encrypted, and ciphertext is longer than the
+-- plaintext limits the columns were sized for.
+ALTER TABLE medicines ALTER COLUMN name TYPE TEXT;
+ALTER TABLE medicines ALTER COLUMN dosage TYPE TEXT;
@@ -0,0 +1,3 @@
+-- Archived medicines are no longer taken: no reminders are generated for them,
+-- but their past reminders stay for adherence history. NULL means active.
+ALTER TABLE medicines ADD COLUMN archived_at TIMESTAMPTZ;
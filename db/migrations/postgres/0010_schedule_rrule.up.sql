@@ -0,0 +1,5 @@
+-- Custom schedules are defined by an RFC 5545 RRULE value (without the
+-- "RRULE:" name), e.g. FREQ=MONTHLY;BYDAY=2TU, and may skip days listed in
+-- exdates as comma-separated YYYY-MM-DD dates. Both are NULL for other frequencies.
+ALTER TABLE schedules ADD COLUMN rrule TEXT;
+ALTER TABLE schedules ADD COLUMN exdates TEXT;
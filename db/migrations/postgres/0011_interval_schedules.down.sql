@@ -0,0 +1,10 @@
+-- Interval schedules cannot be expressed without the new columns, so they are
+-- removed along with their reminders.
+DELETE FROM schedules WHERE frequency = 'interval';
+
+ALTER TABLE schedules DROP COLUMN first_dose_at;
+ALTER TABLE schedules DROP COLUMN interval_hours;
+
+ALTER TABLE schedules DROP CONSTRAINT schedules_frequency_check;
+ALTER TABLE schedules ADD CONSTRAINT schedules_frequency_check
+    CHECK (frequency IN ('daily','weekly','custom'));
@@ -0,0 +1,9 @@
+-- Interval schedules ("every 8 hours") have no intake times: doses follow each
+-- other every interval_hours, counted from the last dose taken or, before
+-- any, from first_dose_at.
+ALTER TABLE schedules DROP CONSTRAINT schedules_frequency_check;
+ALTER TABLE schedules ADD CONSTRAINT schedules_frequency_check
+    CHECK (frequency IN ('daily','weekly','custom','interval'));
+
+ALTER TABLE schedules ADD COLUMN interval_hours INTEGER CHECK (interval_hours BETWEEN 1 AND 168);
+ALTER TABLE schedules ADD COLUMN first_dose_at TIMESTAMPTZ;
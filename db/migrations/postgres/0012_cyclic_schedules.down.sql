@@ -0,0 +1,10 @@
+-- Cyclic schedules cannot be expressed without the new columns, so they are
+-- removed along with their reminders.
+DELETE FROM schedules WHERE frequency = 'cyclic';
+
+ALTER TABLE schedules DROP COLUMN cycle_rest_days;
+ALTER TABLE schedules DROP COLUMN cycle_active_days;
+
+ALTER TABLE schedules DROP CONSTRAINT schedules_frequency_check;
+ALTER TABLE schedules ADD CONSTRAINT schedules_frequency_check
+    CHECK (frequency IN ('daily','weekly','custom','interval'));
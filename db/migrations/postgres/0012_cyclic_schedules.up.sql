@@ -0,0 +1,8 @@
+-- Cyclic schedules repeat cycle_active_days days of doses followed by
+-- cycle_rest_days days without, counted from start_date (e.g. 21 on, 7 off).
+ALTER TABLE schedules DROP CONSTRAINT schedules_frequency_check;
+ALTER TABLE schedules ADD CONSTRAINT schedules_frequency_check
+    CHECK (frequency IN ('daily','weekly','custom','interval','cyclic'));
+
+ALTER TABLE schedules ADD COLUMN cycle_active_days INTEGER CHECK (cycle_active_days BETWEEN 1 AND 365);
+ALTER TABLE schedules ADD COLUMN cycle_rest_days INTEGER CHECK (cycle_rest_days BETWEEN 1 AND 365);
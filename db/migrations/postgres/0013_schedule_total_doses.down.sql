@@ -0,0 +1 @@
+ALTER TABLE schedules DROP COLUMN total_doses;
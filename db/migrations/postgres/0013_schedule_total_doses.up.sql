@@ -0,0 +1,3 @@
+-- Fixed courses ("take 14 doses") end once total_doses doses are taken or
+-- still pending; missed doses are made up at the end. NULL for open-ended schedules.
+ALTER TABLE schedules ADD COLUMN total_doses INTEGER CHECK (total_doses >= 1);
@@ -0,0 +1,16 @@
+-- Dose steps taper or titrate a schedule: from start_date through end_date
+-- (or until the next step when NULL) each dose is the step's amount. dose is
+-- health data, encrypted with the owner's data key like medicines.dosage.
+CREATE TABLE IF NOT EXISTS schedule_dose_steps (
+    step_id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    dose TEXT NOT NULL,                  -- e.g. "40 mg"
+    UNIQUE (schedule_id, start_date),
+    FOREIGN KEY (schedule_id) REFERENCES schedules(schedule_id) ON DELETE CASCADE
+);
+
+-- The dose due, copied from the step when the reminder is generated so it
+-- stays as it was if the steps change later
+ALTER TABLE reminders ADD COLUMN dose TEXT;
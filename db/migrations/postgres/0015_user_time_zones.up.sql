@@ -0,0 +1,3 @@
+-- The IANA time zone (e.g. "Europe/Berlin") a user's intake times and dates
+-- are wall-clock times in. NULL keeps the server's zone, as before.
+ALTER TABLE users ADD COLUMN time_zone TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE reminders DROP COLUMN version_id;
+DROP TABLE IF EXISTS schedule_versions;
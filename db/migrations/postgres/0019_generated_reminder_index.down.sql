@@ -0,0 +1,5 @@
+-- Restores the index over all reminders. This fails while reminders created
+-- through the API share an instant with another reminder of their schedule;
+-- those have to be resolved by hand first, as they are patient history.
+DROP INDEX IF EXISTS idx_reminders_schedule_datetime;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reminders_schedule_datetime ON reminders (schedule_id, reminder_datetime);
@@ -0,0 +1,6 @@
+-- The unique index from 0002 covers every reminder, so a reminder created
+-- through the API at the instant of another is refused. Only the generator
+-- relies on it, to run again safely, so limit it to generated reminders.
+DROP INDEX IF EXISTS idx_reminders_schedule_datetime;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reminders_schedule_datetime ON reminders (schedule_id, reminder_datetime)
+WHERE source = 'generated';
@@ -1,4 +1,7 @@
-CREATE TABLE users (
+-- Baseline schema. IF NOT EXISTS lets databases created by hand from the old
+-- sql/init.sql be adopted without losing data.
+
+CREATE TABLE IF NOT EXISTS users (
     user_id INTEGER PRIMARY KEY AUTOINCREMENT,
     name VARCHAR(100) NOT NULL,
     email VARCHAR(150) UNIQUE NOT NULL,
@@ -7,7 +10,7 @@ CREATE TABLE users (
 );
 
 
-CREATE TABLE medicines (
+CREATE TABLE IF NOT EXISTS medicines (
     medicine_id INTEGER PRIMARY KEY AUTOINCREMENT,
     user_id INTEGER NOT NULL,
     name VARCHAR(100) NOT NULL,
@@ -19,7 +22,7 @@ CREATE TABLE medicines (
 );
 
 
-CREATE TABLE schedules (
+CREATE TABLE IF NOT EXISTS schedules (
     schedule_id INTEGER PRIMARY KEY AUTOINCREMENT,
     medicine_id INTEGER NOT NULL,
     start_date DATE NOT NULL,
@@ -30,7 +33,7 @@ CREATE TABLE schedules (
 );
 
 
-CREATE TABLE schedule_times (
+CREATE TABLE IF NOT EXISTS schedule_times (
     time_id INTEGER PRIMARY KEY AUTOINCREMENT,
     schedule_id INTEGER NOT NULL,
     intake_time TIME NOT NULL,           -- e.g. "08:00", "14:00"
@@ -38,7 +41,7 @@ CREATE TABLE schedule_times (
 );
 
 
-CREATE TABLE reminders (
+CREATE TABLE IF NOT EXISTS reminders (
     reminder_id INTEGER PRIMARY KEY AUTOINCREMENT,
     schedule_id INTEGER NOT NULL,
     reminder_datetime DATETIME NOT NULL,
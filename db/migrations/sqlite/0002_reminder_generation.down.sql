@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_reminders_schedule_datetime;
+ALTER TABLE schedules DROP COLUMN generated_until;
+ALTER TABLE reminders DROP COLUMN source;
+ALTER TABLE reminders DROP COLUMN time_id;
@@ -0,0 +1,14 @@
+-- Reminders produced by the generator remember which intake time they came
+-- from, so they can be told apart from reminders created through the API.
+ALTER TABLE reminders ADD COLUMN time_id INTEGER;
+ALTER TABLE reminders ADD COLUMN source TEXT NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'generated'));
+
+-- How far ahead reminders have been materialized for each schedule.
+ALTER TABLE schedules ADD COLUMN generated_until DATETIME;
+
+-- A schedule can only have one reminder at a given instant.
+DELETE FROM reminders
+WHERE reminder_id NOT IN (
+    SELECT MIN(reminder_id) FROM reminders GROUP BY schedule_id, reminder_datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reminders_schedule_datetime ON reminders (schedule_id, reminder_datetime);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS notification_attempts;
+DROP INDEX IF EXISTS idx_reminders_status_datetime;
+ALTER TABLE reminders DROP COLUMN next_notify_at;
+ALTER TABLE reminders DROP COLUMN notify_attempts;
+ALTER TABLE reminders DROP COLUMN notified_at;
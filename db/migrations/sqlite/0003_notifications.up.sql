@@ -0,0 +1,20 @@
+-- Delivery state of each reminder. next_notify_at doubles as a claim lease and
+-- retry backoff so several dispatchers never notify the same reminder at once.
+ALTER TABLE reminders ADD COLUMN notified_at DATETIME;
+ALTER TABLE reminders ADD COLUMN notify_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE reminders ADD COLUMN next_notify_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_reminders_status_datetime ON reminders (status, reminder_datetime);
+
+-- One row per attempt to deliver a reminder through a notifier.
+CREATE TABLE IF NOT EXISTS notification_attempts (
+    attempt_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reminder_id INTEGER NOT NULL,
+    notifier TEXT NOT NULL,
+    attempted_at DATETIME NOT NULL,
+    success BOOLEAN NOT NULL,
+    error TEXT,
+    FOREIGN KEY (reminder_id) REFERENCES reminders(reminder_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_attempts_reminder ON notification_attempts (reminder_id);
@@ -0,0 +1,4 @@
+-- Medicine fields are stored encrypted from here on. SQLite does not enforce
+-- VARCHAR lengths, so unlike Postgres the columns need no widening; this
+-- migration only keeps the version numbers of both dialects aligned.
+SELECT 1;
@@ -0,0 +1,2 @@
+-- Destroys every data key: values encrypted with them become unreadable.
+DROP TABLE IF EXISTS user_keys;
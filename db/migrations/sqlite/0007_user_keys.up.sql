@@ -0,0 +1,8 @@
+-- Per-user data-encryption keys, stored wrapped by the master ENCRYPTION_KEY.
+-- Deleting a user's row here crypto-shreds everything encrypted with their key.
+CREATE TABLE IF NOT EXISTS user_keys (
+    user_id INTEGER PRIMARY KEY,
+    wrapped_key TEXT NOT NULL,           -- "<master key id>:<ciphertext>"
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE medicines DROP COLUMN archived_at;
@@ -0,0 +1,7 @@
+-- Days of the week a weekly schedule runs on, as a bitmask with bit n set for
+-- weekday n (0 = Sunday). 0 for schedules of other frequencies.
+ALTER TABLE schedules ADD COLUMN weekdays INTEGER NOT NULL DEFAULT 0 CHECK (weekdays BETWEEN 0 AND 127);
+
+-- Weekly schedules so far repeated on the weekday they started
+UPDATE schedules SET weekdays = 1 << CAST(strftime('%w', start_date) AS INTEGER)
+WHERE frequency = 'weekly';
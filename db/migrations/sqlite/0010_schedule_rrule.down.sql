@@ -0,0 +1,2 @@
+ALTER TABLE schedules DROP COLUMN exdates;
+ALTER TABLE schedules DROP COLUMN rrule;
@@ -0,0 +1,30 @@
+-- migrate:no-foreign-keys
+-- Interval schedules ("every 8 hours") have no intake times: doses follow each
+-- other every interval_hours, counted from the last dose taken or, before
+-- any, from first_dose_at. SQLite cannot change a CHECK constraint in place,
+-- so the table is rebuilt to allow the new frequency.
+CREATE TABLE schedules_new (
+    schedule_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    medicine_id INTEGER NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily','weekly','custom','interval')),
+    times_per_day INTEGER NOT NULL,
+    generated_until DATETIME,
+    grace_minutes INTEGER NOT NULL DEFAULT 120 CHECK (grace_minutes >= 0),
+    weekdays INTEGER NOT NULL DEFAULT 0 CHECK (weekdays BETWEEN 0 AND 127),
+    rrule TEXT,
+    exdates TEXT,
+    interval_hours INTEGER CHECK (interval_hours BETWEEN 1 AND 168),
+    first_dose_at DATETIME,
+    FOREIGN KEY (medicine_id) REFERENCES medicines(medicine_id) ON DELETE CASCADE
+);
+
+INSERT INTO schedules_new (schedule_id, medicine_id, start_date, end_date, frequency, times_per_day,
+                           generated_until, grace_minutes, weekdays, rrule, exdates)
+SELECT schedule_id, medicine_id, start_date, end_date, frequency, times_per_day,
+       generated_until, grace_minutes, weekdays, rrule, exdates
+FROM schedules;
+
+DROP TABLE schedules;
+ALTER TABLE schedules_new RENAME TO schedules;
@@ -0,0 +1,38 @@
+-- migrate:no-foreign-keys
+-- Cyclic schedules cannot be expressed without the new columns, so they are
+-- removed with everything that hangs off them. Foreign keys are off, so the
+-- cascade is done by hand.
+DELETE FROM notification_attempts WHERE reminder_id IN (
+    SELECT reminder_id FROM reminders WHERE schedule_id IN (
+        SELECT schedule_id FROM schedules WHERE frequency = 'cyclic'));
+DELETE FROM reminders WHERE schedule_id IN (SELECT schedule_id FROM schedules WHERE frequency = 'cyclic');
+DELETE FROM schedule_times WHERE schedule_id IN (SELECT schedule_id FROM schedules WHERE frequency = 'cyclic');
+DELETE FROM schedules WHERE frequency = 'cyclic';
+
+CREATE TABLE schedules_old (
+    schedule_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    medicine_id INTEGER NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily','weekly','custom','interval')),
+    times_per_day INTEGER NOT NULL,
+    generated_until DATETIME,
+    grace_minutes INTEGER NOT NULL DEFAULT 120 CHECK (grace_minutes >= 0),
+    weekdays INTEGER NOT NULL DEFAULT 0 CHECK (weekdays BETWEEN 0 AND 127),
+    rrule TEXT,
+    exdates TEXT,
+    interval_hours INTEGER CHECK (interval_hours BETWEEN 1 AND 168),
+    first_dose_at DATETIME,
+    FOREIGN KEY (medicine_id) REFERENCES medicines(medicine_id) ON DELETE CASCADE
+);
+
+INSERT INTO schedules_old (schedule_id, medicine_id, start_date, end_date, frequency, times_per_day,
+                           generated_until, grace_minutes, weekdays, rrule, exdates,
+                           interval_hours, first_dose_at)
+SELECT schedule_id, medicine_id, start_date, end_date, frequency, times_per_day,
+       generated_until, grace_minutes, weekdays, rrule, exdates,
+       interval_hours, first_dose_at
+FROM schedules;
+
+DROP TABLE schedules;
+ALTER TABLE schedules_old RENAME TO schedules;
@@ -0,0 +1,34 @@
+-- migrate:no-foreign-keys
+-- Cyclic schedules repeat cycle_active_days days of doses followed by
+-- cycle_rest_days days without, counted from start_date (e.g. 21 on, 7 off).
+-- SQLite cannot change a CHECK constraint in place, so the table is rebuilt
+-- to allow the new frequency.
+CREATE TABLE schedules_new (
+    schedule_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    medicine_id INTEGER NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily','weekly','custom','interval','cyclic')),
+    times_per_day INTEGER NOT NULL,
+    generated_until DATETIME,
+    grace_minutes INTEGER NOT NULL DEFAULT 120 CHECK (grace_minutes >= 0),
+    weekdays INTEGER NOT NULL DEFAULT 0 CHECK (weekdays BETWEEN 0 AND 127),
+    rrule TEXT,
+    exdates TEXT,
+    interval_hours INTEGER CHECK (interval_hours BETWEEN 1 AND 168),
+    first_dose_at DATETIME,
+    cycle_active_days INTEGER CHECK (cycle_active_days BETWEEN 1 AND 365),
+    cycle_rest_days INTEGER CHECK (cycle_rest_days BETWEEN 1 AND 365),
+    FOREIGN KEY (medicine_id) REFERENCES medicines(medicine_id) ON DELETE CASCADE
+);
+
+INSERT INTO schedules_new (schedule_id, medicine_id, start_date, end_date, frequency, times_per_day,
+                           generated_until, grace_minutes, weekdays, rrule, exdates,
+                           interval_hours, first_dose_at)
+SELECT schedule_id, medicine_id, start_date, end_date, frequency, times_per_day,
+       generated_until, grace_minutes, weekdays, rrule, exdates,
+       interval_hours, first_dose_at
+FROM schedules;
+
+DROP TABLE schedules;
+ALTER TABLE schedules_new RENAME TO schedules;
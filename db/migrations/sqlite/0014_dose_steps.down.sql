@@ -0,0 +1,2 @@
+ALTER TABLE reminders DROP COLUMN dose;
+DROP TABLE IF EXISTS schedule_dose_steps;
@@ -0,0 +1,13 @@
+-- A user's trip to another time zone. From start_date through end_date,
+-- policy 'local' reads intake times on the destination's wall clock, while
+-- 'home' keeps them at the same instants as at home. One trip per user;
+-- later days follow the home zone again.
+CREATE TABLE IF NOT EXISTS travel_plans (
+    user_id INTEGER PRIMARY KEY,
+    time_zone TEXT NOT NULL,             -- IANA name of the destination
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    policy TEXT NOT NULL CHECK (policy IN ('home', 'local')),
+    CHECK (end_date >= start_date),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
@@ -0,0 +1,13 @@
+-- Periods a schedule is on hold, e.g. a blood thinner stopped before surgery.
+-- No reminders are generated from paused_at until resumed_at, which is NULL
+-- until the schedule is resumed or lies ahead when it resumes by itself.
+CREATE TABLE IF NOT EXISTS schedule_pauses (
+    pause_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    schedule_id INTEGER NOT NULL,
+    paused_at DATETIME NOT NULL,
+    resumed_at DATETIME,
+    CHECK (resumed_at IS NULL OR resumed_at > paused_at),
+    FOREIGN KEY (schedule_id) REFERENCES schedules(schedule_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_pauses_schedule ON schedule_pauses (schedule_id);
@@ -0,0 +1,46 @@
+-- Every change to when a schedule's doses are due makes a new version, in
+-- effect from effective_from (NULL for version 1, which applies from the
+-- schedule's start). The columns copy those of schedules, with intake_times
+-- the schedule's "HH:MM" times joined by commas.
+CREATE TABLE IF NOT EXISTS schedule_versions (
+    version_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    schedule_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    effective_from DATETIME,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    frequency TEXT NOT NULL,
+    times_per_day INTEGER NOT NULL,
+    weekdays INTEGER NOT NULL DEFAULT 0,
+    rrule TEXT,
+    exdates TEXT,
+    interval_hours INTEGER,
+    first_dose_at DATETIME,
+    cycle_active_days INTEGER,
+    cycle_rest_days INTEGER,
+    total_doses INTEGER,
+    grace_minutes INTEGER NOT NULL,
+    intake_times TEXT,
+    UNIQUE (schedule_id, version),
+    FOREIGN KEY (schedule_id) REFERENCES schedules(schedule_id) ON DELETE CASCADE
+);
+
+-- The version a generated reminder came from. Versions only go away with
+-- their schedule, which takes its reminders along.
+ALTER TABLE reminders ADD COLUMN version_id INTEGER;
+
+-- Existing schedules start out with their current definition as version 1,
+-- which the reminders generated so far are linked to
+INSERT INTO schedule_versions (schedule_id, version, start_date, end_date, frequency, times_per_day, weekdays,
+                               rrule, exdates, interval_hours, first_dose_at, cycle_active_days, cycle_rest_days,
+                               total_doses, grace_minutes, intake_times)
+SELECT s.schedule_id, 1, s.start_date, s.end_date, s.frequency, s.times_per_day, s.weekdays,
+       s.rrule, s.exdates, s.interval_hours, s.first_dose_at, s.cycle_active_days, s.cycle_rest_days,
+       s.total_doses, s.grace_minutes,
+       (SELECT group_concat(t.intake_time, ',' ORDER BY t.intake_time) FROM schedule_times t WHERE t.schedule_id = s.schedule_id)
+FROM schedules s;
+
+UPDATE reminders SET version_id = (
+    SELECT v.version_id FROM schedule_versions v WHERE v.schedule_id = reminders.schedule_id
+)
+WHERE source = 'generated';
@@ -0,0 +1,73 @@
+// handlers/adherence.go
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"pillTickr-backend/adherence"
+	"pillTickr-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAdherenceDays is the range reported when no from date is given
+const defaultAdherenceDays = 30
+
+// GET /adherence?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *Handler) GetAdherence(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		return
+	}
+
+	today, ok := h.userToday(c)
+	if !ok {
+		return
+	}
+	loc := today.Location()
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+	if v := c.Query("to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date in YYYY-MM-DD format"})
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -(defaultAdherenceDays - 1))
+	if v := c.Query("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date in YYYY-MM-DD format"})
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	if to.Sub(from) > 366*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date range cannot exceed one year"})
+		return
+	}
+
+	history, err := h.store.Reminders().ListReminderHistory(c.Request.Context(), userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+		return
+	}
+
+	records := make([]adherence.Record, 0, len(history))
+	for _, r := range history {
+		records = append(records, adherence.Record{
+			MedicineID:   r.MedicineID,
+			MedicineName: r.MedicineName,
+			Due:          r.Reminder.ReminderDatetime,
+			Outcome:      r.Reminder.Outcome,
+		})
+	}
+
+	c.JSON(http.StatusOK, adherence.Compute(records, from, to, loc))
+}
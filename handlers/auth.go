@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
 	"time"
 
-	"pillTickr-backend/db"
+	"pillTickr-backend/models"
+	"pillTickr-backend/store"
 	"pillTickr-backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +20,7 @@ type SignupInput struct {
 	Name     string `json:"name" binding:"required"` // display name
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	TimeZone string `json:"timeZone"` // IANA name, e.g. "Europe/Berlin"; optional
 }
 
 type LoginInput struct {
@@ -33,6 +37,7 @@ type UserResponse struct {
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"createdAt"`
+	TimeZone  string    `json:"timeZone,omitempty"`
 }
 
 type AuthResponse struct {
@@ -47,156 +52,184 @@ type AuthResponse struct {
 var accessTokenExpiry = 30 * time.Minute
 var refreshTokenExpiry = 7 * 24 * time.Hour
 
+// issueRefreshToken stores a refresh token starting a new family (a new login)
+// for userID and returns the raw token
+func (h *Handler) issueRefreshToken(c *gin.Context, userID string) (string, error) {
+	familyID, err := utils.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	token, err := utils.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	err = h.store.Users().CreateRefreshToken(c.Request.Context(), models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenExpiry),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
 // Register new user
-func Register(c *gin.Context) {
+func (h *Handler) Register(c *gin.Context) {
 	var input SignupInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if input.TimeZone != "" {
+		if _, err := models.LoadTimeZone(input.TimeZone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timeZone must be an IANA time zone such as Europe/Berlin"})
+			return
+		}
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 		return
 	}
 
-	var userID string
-	var createdAt time.Time
-	err = db.DB.QueryRow(
-		`INSERT INTO users (name, email, password_hash, created_at) 
-		 VALUES ($1, $2, $3, $4) RETURNING user_id, created_at`,
-		input.Name, input.Email, string(hashedPassword), time.Now(),
-	).Scan(&userID, &createdAt)
+	user := models.User{Name: input.Name, Email: input.Email, PasswordHash: string(hashedPassword), TimeZone: input.TimeZone}
+	err = h.store.Users().CreateUser(c.Request.Context(), &user)
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user", "details": err.Error()})
 		return
 	}
 
-	accessToken, accessExp, err := utils.GenerateJWT(userID, input.Email, accessTokenExpiry)
+	accessToken, accessExp, err := utils.GenerateJWT(user.ID, user.Email, accessTokenExpiry)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new access token"})
 		return
 	}
 
-	refreshToken, _, err := utils.GenerateJWT(userID, input.Email, refreshTokenExpiry)
+	refreshToken, err := h.issueRefreshToken(c, user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new refresh token"})
 		return
 	}
 
-	// Optionally store refresh token in DB
-	// _, _ = db.DB.Exec(`UPDATE users SET refresh_token = $1 WHERE user_id = $2`, refreshToken, userID)
-
-	resp := AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int(accessTokenExpiry.Seconds()),
-		TokenType:    "bearer",
-		ExpiresAt:    accessExp,
-		User: UserResponse{
-			ID:        userID,
-			Name:      input.Name,
-			Email:     input.Email,
-			CreatedAt: createdAt,
-		},
-	}
-	c.JSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, newAuthResponse(user, accessToken, accessExp, refreshToken))
 }
 
 // Login existing user
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	var input LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var storedHash, name, email, userID string
-	var createdAt time.Time
-	err := db.DB.QueryRow(
-		`SELECT user_id, name, email, password_hash, created_at 
-		 FROM users WHERE email = $1`,
-		input.Email,
-	).Scan(&userID, &name, &email, &storedHash, &createdAt)
+	user, err := h.store.Users().GetUserByEmail(c.Request.Context(), input.Email)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(input.Password)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)); err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	accessToken, accessExp, err := utils.GenerateJWT(userID, email, accessTokenExpiry)
+	accessToken, accessExp, err := utils.GenerateJWT(user.ID, user.Email, accessTokenExpiry)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new access token"})
 		return
 	}
-	refreshToken, _, err := utils.GenerateJWT(userID, email, refreshTokenExpiry)
+
+	// Each login starts a new refresh token family
+	if err := h.store.Users().DeleteExpiredRefreshTokens(c.Request.Context(), user.ID, time.Now().UTC()); err != nil {
+		slog.Warn("Failed to prune expired refresh tokens", "user_id", user.ID, "error", err)
+	}
+	refreshToken, err := h.issueRefreshToken(c, user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new refresh token"})
 		return
 	}
 
-	// Optionally store refresh token
-	_, _ = db.DB.Exec(`UPDATE users SET refresh_token = $1 WHERE user_id = $2`, refreshToken, userID)
+	c.JSON(http.StatusOK, newAuthResponse(user, accessToken, accessExp, refreshToken))
+}
 
-	resp := AuthResponse{
+// newAuthResponse builds the token response returned by Register and Login
+func newAuthResponse(user models.User, accessToken string, accessExp int64, refreshToken string) AuthResponse {
+	return AuthResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		ExpiresIn:    int(accessTokenExpiry.Seconds()),
 		TokenType:    "bearer",
 		ExpiresAt:    accessExp,
-		User: UserResponse{
-			ID:        userID,
-			Name:      name,
-			Email:     email,
-			CreatedAt: createdAt,
-		},
-	}
-	c.JSON(http.StatusOK, resp)
+		User:         newUserResponse(user),
+	}
+}
+
+func newUserResponse(user models.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+		TimeZone:  user.TimeZone,
+	}
 }
 
-func RefreshToken(c *gin.Context) {
+// RefreshToken rotates a refresh token and issues a new access token
+func (h *Handler) RefreshToken(c *gin.Context) {
 	var input RefreshInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	token, claims, err := utils.ParseJWT(input.RefreshToken)
-	if err != nil || !token.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+	newRefreshToken, err := utils.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new refresh token"})
 		return
 	}
+	now := time.Now().UTC()
 
-	userID := claims["id"].(string)
-	email := claims["email"].(string)
-
-	// // Verify token exists in DB (optional but secure)
-	// var storedToken string
-	// err = db.DB.QueryRow(`SELECT refresh_token FROM users WHERE user_id = $1`, userID).Scan(&storedToken)
-	// if err != nil || storedToken != input.RefreshToken {
-	// 	c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token mismatch"})
-	// 	return
-	// }
-
-	// Generate new tokens
-	newAccessToken, accessExp, err := utils.GenerateJWT(userID, email, accessTokenExpiry)
+	userID, err := h.store.Users().RotateRefreshToken(c.Request.Context(), utils.HashToken(input.RefreshToken), models.RefreshToken{
+		TokenHash: utils.HashToken(newRefreshToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenExpiry),
+	})
+	if errors.Is(err, store.ErrRefreshTokenReused) {
+		slog.Warn("Refresh token reuse detected, token family revoked", "user_id", userID, "ip", c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if errors.Is(err, store.ErrRefreshTokenInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new access token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
 		return
 	}
-	newRefreshToken, _, err := utils.GenerateJWT(userID, email, refreshTokenExpiry)
+
+	user, err := h.store.Users().GetUser(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new refresh token"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
 		return
 	}
 
-	// Rotate refresh token
-	_, _ = db.DB.Exec(`UPDATE users SET refresh_token = $1 WHERE user_id = $2`, newRefreshToken, userID)
+	newAccessToken, accessExp, err := utils.GenerateJWT(user.ID, user.Email, accessTokenExpiry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new access token"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"accessToken":  newAccessToken,
@@ -206,3 +239,35 @@ func RefreshToken(c *gin.Context) {
 		"expiresAt":    accessExp,
 	})
 }
+
+// Logout revokes the refresh token family of the presented token. Access
+// tokens already issued stay valid until they expire.
+func (h *Handler) Logout(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.Users().RevokeRefreshTokenFamily(c.Request.Context(), utils.HashToken(input.RefreshToken)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll revokes every refresh token of the authenticated user, signing out all devices
+func (h *Handler) LogoutAll(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Users().RevokeUserRefreshTokens(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"pillTickr-backend/models"
+	"pillTickr-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// doseStepInput is one dose step of a tapering or titration schedule
+type doseStepInput struct {
+	StartDate string  `json:"start_date" binding:"required"`
+	EndDate   *string `json:"end_date"` // omitted = until the next step starts
+	Dose      string  `json:"dose" binding:"required"`
+}
+
+// normalizeDoseSteps parses dose steps and orders them by start date,
+// returning a message for the client if one is invalid or they overlap
+func normalizeDoseSteps(inputs []doseStepInput) ([]models.DoseStep, string) {
+	steps := make([]models.DoseStep, 0, len(inputs))
+	for _, in := range inputs {
+		startDate, err := time.Parse("2006-01-02", in.StartDate)
+		if err != nil {
+			return nil, "dose step start_date must be a date in YYYY-MM-DD format"
+		}
+		step := models.DoseStep{StartDate: startDate, Dose: strings.TrimSpace(in.Dose)}
+		if step.Dose == "" {
+			return nil, "dose step dose must not be empty"
+		}
+		if in.EndDate != nil {
+			endDate, err := time.Parse("2006-01-02", *in.EndDate)
+			if err != nil {
+				return nil, "dose step end_date must be a date in YYYY-MM-DD format"
+			}
+			if endDate.Before(startDate) {
+				return nil, "dose step end_date must not be before its start_date"
+			}
+			step.EndDate = &endDate
+		}
+		steps = append(steps, step)
+	}
+
+	sort.Slice(steps, func(i, j int) bool { return steps[i].StartDate.Before(steps[j].StartDate) })
+	for i := 1; i < len(steps); i++ {
+		prev, cur := steps[i-1], steps[i]
+		if !prev.StartDate.Before(cur.StartDate) {
+			return nil, fmt.Sprintf("two dose steps start on %s", cur.StartDate.Format("2006-01-02"))
+		}
+		if prev.EndDate != nil && !prev.EndDate.Before(cur.StartDate) {
+			return nil, fmt.Sprintf("the dose step starting %s overlaps the one before it", cur.StartDate.Format("2006-01-02"))
+		}
+	}
+	return steps, ""
+}
+
+// GET /schedules/:id/dose-steps
+func (h *Handler) GetDoseSteps(c *gin.Context) {
+	steps, err := h.store.Schedules().ListDoseSteps(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dose steps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, steps)
+}
+
+// PUT /schedules/:id/dose-steps
+// Replaces the whole set of dose steps; an empty list goes back to the
+// medicine's dosage. Future pending reminders are regenerated with the new doses.
+func (h *Handler) ReplaceDoseSteps(c *gin.Context) {
+	scheduleID := c.Param("id")
+
+	var req struct {
+		Steps []doseStepInput `json:"steps" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	steps, msg := normalizeDoseSteps(req.Steps)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	steps, err := h.store.Schedules().ReplaceDoseSteps(c.Request.Context(), scheduleID, steps)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace dose steps"})
+		return
+	}
+
+	h.regenerateReminders(c, scheduleID)
+
+	c.JSON(http.StatusOK, steps)
+}
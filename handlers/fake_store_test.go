@@ -0,0 +1,231 @@
+package handlers_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"pillTickr-backend/authz"
+	"pillTickr-backend/models"
+	"pillTickr-backend/store"
+)
+
+// fakeStore is an in-memory store.Store holding just what the handler tests
+// use. The repositories embed their interfaces, so calling a method the fake
+// does not implement panics and points at what is missing.
+type fakeStore struct {
+	mu        sync.Mutex
+	nextID    int
+	users     map[string]models.User
+	medicines map[string]models.Medicine
+	schedules map[string]models.Schedule
+	times     map[string]models.ScheduleTime
+	reminders map[string]models.Reminder
+
+	// err, when set, is returned by every write, like a failing database
+	err error
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:     map[string]models.User{},
+		medicines: map[string]models.Medicine{},
+		schedules: map[string]models.Schedule{},
+		times:     map[string]models.ScheduleTime{},
+		reminders: map[string]models.Reminder{},
+	}
+}
+
+// id hands out the next ID; callers hold mu
+func (f *fakeStore) id() string {
+	f.nextID++
+	return strconv.Itoa(f.nextID)
+}
+
+// addUser, addMedicine, addSchedule and addReminder seed the store
+func (f *fakeStore) addUser(name string) models.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u := models.User{ID: f.id(), Name: name, Email: name + "@example.com"}
+	f.users[u.ID] = u
+	return u
+}
+
+func (f *fakeStore) addMedicine(userID, name string) models.Medicine {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := models.Medicine{ID: f.id(), UserID: userID, Name: name}
+	f.medicines[m.ID] = m
+	return m
+}
+
+func (f *fakeStore) addSchedule(medicineID string) models.Schedule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := models.Schedule{ID: f.id(), MedicineID: medicineID, Frequency: "daily", TimesPerDay: 1,
+		StartDate: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), GraceMinutes: models.DefaultGraceMinutes}
+	f.schedules[s.ID] = s
+	return s
+}
+
+func (f *fakeStore) addReminder(scheduleID string, due time.Time) models.Reminder {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := models.Reminder{ID: f.id(), ScheduleID: scheduleID, ReminderDatetime: due, Status: "pending", Source: "manual"}
+	f.reminders[r.ID] = r
+	return r
+}
+
+func (f *fakeStore) Users() store.UserStore         { return fakeUsers{f: f} }
+func (f *fakeStore) Medicines() store.MedicineStore { return fakeMedicines{f: f} }
+func (f *fakeStore) Schedules() store.ScheduleStore { return fakeSchedules{f: f} }
+func (f *fakeStore) Reminders() store.ReminderStore { return fakeReminders{f: f} }
+
+// WithTx runs fn directly; the fake has nothing to roll back
+func (f *fakeStore) WithTx(ctx context.Context, fn func(tx store.Store) error) error {
+	return fn(f)
+}
+
+func (f *fakeStore) OwnerOf(ctx context.Context, resource authz.Resource, id string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	scheduleID := id
+	switch resource {
+	case authz.Medicine:
+		if m, ok := f.medicines[id]; ok {
+			return m.UserID, nil
+		}
+		return "", authz.ErrNotFound
+	case authz.ScheduleTime:
+		t, ok := f.times[id]
+		if !ok {
+			return "", authz.ErrNotFound
+		}
+		scheduleID = t.ScheduleID
+	case authz.Reminder:
+		r, ok := f.reminders[id]
+		if !ok {
+			return "", authz.ErrNotFound
+		}
+		scheduleID = r.ScheduleID
+	}
+	s, ok := f.schedules[scheduleID]
+	if !ok {
+		return "", authz.ErrNotFound
+	}
+	return f.medicines[s.MedicineID].UserID, nil
+}
+
+type fakeUsers struct {
+	store.UserStore
+	f *fakeStore
+}
+
+func (u fakeUsers) GetUser(ctx context.Context, id string) (models.User, error) {
+	u.f.mu.Lock()
+	defer u.f.mu.Unlock()
+	user, ok := u.f.users[id]
+	if !ok {
+		return user, store.ErrNotFound
+	}
+	return user, nil
+}
+
+type fakeMedicines struct {
+	store.MedicineStore
+	f *fakeStore
+}
+
+func (m fakeMedicines) IsMedicineArchived(ctx context.Context, id string) (bool, error) {
+	m.f.mu.Lock()
+	defer m.f.mu.Unlock()
+	medicine, ok := m.f.medicines[id]
+	if !ok {
+		return false, store.ErrNotFound
+	}
+	return medicine.ArchivedAt != nil, nil
+}
+
+type fakeSchedules struct {
+	store.ScheduleStore
+	f *fakeStore
+}
+
+func (s fakeSchedules) GetSchedule(ctx context.Context, id string) (models.Schedule, error) {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	schedule, ok := s.f.schedules[id]
+	if !ok {
+		return schedule, store.ErrNotFound
+	}
+	return schedule, nil
+}
+
+func (s fakeSchedules) CreateSchedule(ctx context.Context, sc *models.Schedule) error {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	if s.f.err != nil {
+		return s.f.err
+	}
+	sc.ID = s.f.id()
+	s.f.schedules[sc.ID] = *sc
+	return nil
+}
+
+func (s fakeSchedules) CreateScheduleTime(ctx context.Context, t *models.ScheduleTime) error {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	t.ID = s.f.id()
+	s.f.times[t.ID] = *t
+	return nil
+}
+
+func (s fakeSchedules) ReplaceDoseSteps(ctx context.Context, scheduleID string, steps []models.DoseStep) ([]models.DoseStep, error) {
+	return steps, nil
+}
+
+func (s fakeSchedules) RecordScheduleVersion(ctx context.Context, scheduleID string, at time.Time) (models.ScheduleVersion, error) {
+	return models.ScheduleVersion{ScheduleID: scheduleID}, nil
+}
+
+type fakeReminders struct {
+	store.ReminderStore
+	f *fakeStore
+}
+
+func (r fakeReminders) GetReminder(ctx context.Context, id string) (models.Reminder, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	reminder, ok := r.f.reminders[id]
+	if !ok {
+		return reminder, store.ErrNotFound
+	}
+	return reminder, nil
+}
+
+func (r fakeReminders) UpdateReminder(ctx context.Context, reminder models.Reminder) error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if r.f.err != nil {
+		return r.f.err
+	}
+	stored, ok := r.f.reminders[reminder.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	stored.ReminderDatetime, stored.Status, stored.TakenAt = reminder.ReminderDatetime, reminder.Status, reminder.TakenAt
+	r.f.reminders[reminder.ID] = stored
+	return nil
+}
+
+func (r fakeReminders) DeleteReminder(ctx context.Context, id string) error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if _, ok := r.f.reminders[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(r.f.reminders, id)
+	return nil
+}
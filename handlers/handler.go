@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"pillTickr-backend/scheduler"
+	"pillTickr-backend/store"
+	"pillTickr-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the API on top of injected storage, so tests can swap in fakes
+type Handler struct {
+	store     store.Store
+	generator *scheduler.Generator
+}
+
+// New creates the API handlers. generator may be nil, in which case schedule
+// changes are only picked up by the generator's next background run.
+func New(st store.Store, generator *scheduler.Generator) *Handler {
+	return &Handler{store: st, generator: generator}
+}
+
+// regenerateReminders refreshes a schedule's future reminders. A failure is
+// only logged: the change itself is saved and the next generator run retries.
+func (h *Handler) regenerateReminders(c *gin.Context, scheduleID string) {
+	if h.generator == nil {
+		return
+	}
+	if _, err := h.generator.Regenerate(c.Request.Context(), scheduleID); err != nil {
+		slog.Error("Failed to regenerate reminders", "schedule_id", scheduleID, "error", err)
+	}
+}
+
+// regenerateUserReminders refreshes the future reminders of every schedule of
+// a user's active medicines, e.g. after their time zone changed
+func (h *Handler) regenerateUserReminders(c *gin.Context, userID string) {
+	medicines, err := h.store.Medicines().ListMedicines(c.Request.Context(), userID, false)
+	if err != nil {
+		slog.Error("Failed to list medicines to regenerate", "user_id", userID, "error", err)
+		return
+	}
+	for _, m := range medicines {
+		schedules, err := h.store.Schedules().ListSchedules(c.Request.Context(), m.ID)
+		if err != nil {
+			slog.Error("Failed to list schedules to regenerate", "medicine_id", m.ID, "error", err)
+			continue
+		}
+		for _, s := range schedules {
+			h.regenerateReminders(c, s.ID)
+		}
+	}
+}
+
+// userToday returns the current time in the signed-in user's time zone, so
+// dates are read off their calendar rather than the server's. On failure it
+// has already responded.
+func (h *Handler) userToday(c *gin.Context) (time.Time, bool) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		return time.Time{}, false
+	}
+	user, err := h.store.Users().GetUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user time zone"})
+		return time.Time{}, false
+	}
+	return time.Now().In(user.Location(time.Local)), true
+}
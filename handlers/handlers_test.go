@@ -0,0 +1,230 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pillTickr-backend/handlers"
+	"pillTickr-backend/models"
+	"pillTickr-backend/routes"
+	"pillTickr-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testAPI serves the routes over a fake store, with two users: alice, who
+// owns a medicine on a schedule with one reminder, and bob, who owns nothing
+type testAPI struct {
+	t          *testing.T
+	st         *fakeStore
+	server     *gin.Engine
+	alice, bob string // bearer tokens
+	medicine   models.Medicine
+	schedule   models.Schedule
+	reminder   models.Reminder
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	st := newFakeStore()
+	api := &testAPI{t: t, st: st, server: gin.New()}
+	routes.AttachRoutes(api.server.Group("/api"), routes.NewRoutes(handlers.New(st, nil)), st)
+
+	alice, bob := st.addUser("alice"), st.addUser("bob")
+	api.alice, api.bob = api.token(alice), api.token(bob)
+	api.medicine = st.addMedicine(alice.ID, "Aspirin")
+	api.schedule = st.addSchedule(api.medicine.ID)
+	api.reminder = st.addReminder(api.schedule.ID, time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC))
+	return api
+}
+
+func (api *testAPI) token(u models.User) string {
+	token, _, err := utils.GenerateJWT(u.ID, u.Email, time.Hour)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	return token
+}
+
+// do sends a request as the user holding token ("" for none) and returns the
+// status and the "error" field of the response, if any
+func (api *testAPI) do(token, method, path, body string) (int, string) {
+	api.t.Helper()
+	req := httptest.NewRequest(method, "/api"+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	api.server.ServeHTTP(w, req)
+
+	var resp struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Error
+}
+
+func TestOwnership(t *testing.T) {
+	api := newTestAPI(t)
+	update := `{"reminder_datetime": "2025-06-01T09:00:00Z", "status": "taken"}`
+	schedule := `{"frequency": "daily", "start_date": "2025-06-01", "times_per_day": 1}`
+
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+	}{
+		{"get schedule", "GET", "/schedules/" + api.schedule.ID, ""},
+		{"delete schedule", "DELETE", "/schedules/" + api.schedule.ID, ""},
+		{"update reminder", "PATCH", "/reminders/" + api.reminder.ID, update},
+		{"delete reminder", "DELETE", "/reminders/" + api.reminder.ID, ""},
+		{"get medicine", "GET", "/medicines/" + api.medicine.ID, ""},
+		{"add schedule to medicine", "POST", "/medicines/" + api.medicine.ID + "/schedules", schedule},
+		{"pause schedule", "POST", "/schedules/" + api.schedule.ID + "/pause", "{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Someone else's record looks exactly like a missing one
+			if code, msg := api.do(api.bob, tt.method, tt.path, tt.body); code != http.StatusNotFound || msg != "Resource not found" {
+				t.Errorf("as another user: %d %q, want 404", code, msg)
+			}
+			missing := strings.Replace(tt.path, "/"+api.reminder.ID, "/999", 1)
+			missing = strings.Replace(missing, "/"+api.schedule.ID, "/999", 1)
+			missing = strings.Replace(missing, "/"+api.medicine.ID, "/999", 1)
+			if code, _ := api.do(api.alice, tt.method, missing, tt.body); code != http.StatusNotFound {
+				t.Errorf("missing record: %d, want 404", code)
+			}
+			if code, _ := api.do("", tt.method, tt.path, tt.body); code != http.StatusUnauthorized {
+				t.Errorf("without a token: %d, want 401", code)
+			}
+		})
+	}
+
+	// None of it touched alice's records
+	if len(api.st.reminders) != 1 || len(api.st.schedules) != 1 || api.st.reminders[api.reminder.ID].Status != "pending" {
+		t.Error("a request for another user's record changed it")
+	}
+}
+
+func TestCreateReminderForAnotherUsersSchedule(t *testing.T) {
+	api := newTestAPI(t)
+	body := `{"schedule_id": "` + api.schedule.ID + `", "reminder_datetime": "2025-06-02T08:00:00Z"}`
+	if code, msg := api.do(api.bob, "POST", "/reminders", body); code != http.StatusNotFound || msg != "Schedule not found" {
+		t.Errorf("got %d %q, want 404", code, msg)
+	}
+}
+
+func TestUpdateReminder(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantMsg  string // part of the error
+	}{
+		{"taken", `{"reminder_datetime": "2025-06-01T10:00:00+02:00", "status": "taken"}`, http.StatusOK, ""},
+		{"missed", `{"reminder_datetime": "2025-06-01T08:00:00Z", "status": "missed"}`, http.StatusOK, ""},
+		{"unknown status", `{"reminder_datetime": "2025-06-01T08:00:00Z", "status": "done"}`, http.StatusBadRequest, "oneof"},
+		{"no status", `{"reminder_datetime": "2025-06-01T08:00:00Z"}`, http.StatusBadRequest, "status is required"},
+		{"no datetime", `{"status": "taken"}`, http.StatusBadRequest, "Reminder datetime is required"},
+		{"malformed", `{"status": `, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			code, msg := api.do(api.alice, "PATCH", "/reminders/"+api.reminder.ID, tt.body)
+			if code != tt.wantCode || !strings.Contains(msg, tt.wantMsg) {
+				t.Fatalf("got %d %q, want %d %q", code, msg, tt.wantCode, tt.wantMsg)
+			}
+
+			stored := api.st.reminders[api.reminder.ID]
+			if tt.wantCode != http.StatusOK {
+				if stored.Status != "pending" {
+					t.Errorf("rejected update changed the status to %q", stored.Status)
+				}
+				return
+			}
+			if !stored.ReminderDatetime.Equal(time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)) || stored.ReminderDatetime.Location() != time.UTC {
+				t.Errorf("stored datetime %v, want 08:00 UTC", stored.ReminderDatetime)
+			}
+			if (stored.Status == "taken") != (stored.TakenAt != nil) {
+				t.Errorf("status %q with taken_at %v", stored.Status, stored.TakenAt)
+			}
+		})
+	}
+}
+
+func TestUpdateReminderHidesStoreErrors(t *testing.T) {
+	api := newTestAPI(t)
+	api.st.err = errors.New(`pq: relation "reminders" does not exist`)
+
+	code, msg := api.do(api.alice, "PATCH", "/reminders/"+api.reminder.ID, `{"reminder_datetime": "2025-06-01T08:00:00Z", "status": "taken"}`)
+	if code != http.StatusInternalServerError || msg != "Failed to update reminder" {
+		t.Errorf("got %d %q, want 500 with a generic message", code, msg)
+	}
+}
+
+func TestCreateScheduleValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantMsg string // part of the error
+	}{
+		{"no frequency", `{"start_date": "2025-06-01", "times_per_day": 1}`, "Frequency"},
+		{"unknown frequency", `{"frequency": "hourly", "start_date": "2025-06-01", "times_per_day": 1}`, "frequency must be one of"},
+		{"no start date", `{"frequency": "daily", "times_per_day": 1}`, "start_date is required"},
+		{"bad start date", `{"frequency": "daily", "start_date": "01/06/2025", "times_per_day": 1}`, "start_date must be a date"},
+		{"end before start", `{"frequency": "daily", "start_date": "2025-06-10", "end_date": "2025-06-01", "times_per_day": 1}`, "end_date must not be before start_date"},
+		{"no times per day", `{"frequency": "daily", "start_date": "2025-06-01"}`, "times_per_day must be at least 1"},
+		{"too many times", `{"frequency": "daily", "start_date": "2025-06-01", "times_per_day": 1, "times": ["08:00", "20:00"]}`, "more entries than times_per_day"},
+		{"repeated time", `{"frequency": "daily", "start_date": "2025-06-01", "times_per_day": 2, "times": ["08:00", "08:00"]}`, "given twice"},
+		{"negative grace", `{"frequency": "daily", "start_date": "2025-06-01", "times_per_day": 1, "grace_minutes": -5}`, "GraceMinutes"},
+		{"weekdays on daily", `{"frequency": "daily", "start_date": "2025-06-01", "times_per_day": 1, "weekdays": ["mon"]}`, "weekdays can only be set on weekly"},
+		{"custom without rrule", `{"frequency": "custom", "start_date": "2025-06-01", "times_per_day": 1}`, "custom schedules need an rrule"},
+		{"invalid rrule", `{"frequency": "custom", "start_date": "2025-06-01", "times_per_day": 1, "rrule": "FREQ=HOURLY"}`, "rrule: "},
+		{"interval without first dose", `{"frequency": "interval", "interval_hours": 8}`, "interval schedules need first_dose_at"},
+		{"interval too long", `{"frequency": "interval", "interval_hours": 200, "first_dose_at": "2025-06-01T08:00:00Z"}`, "interval_hours between 1 and 168"},
+		{"cyclic without rest days", `{"frequency": "cyclic", "start_date": "2025-06-01", "times_per_day": 1, "cycle_active_days": 21}`, "cycle_rest_days between 1 and 365"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			code, msg := api.do(api.alice, "POST", "/medicines/"+api.medicine.ID+"/schedules", tt.body)
+			if code != http.StatusBadRequest || !strings.Contains(msg, tt.wantMsg) {
+				t.Errorf("got %d %q, want 400 %q", code, msg, tt.wantMsg)
+			}
+			if len(api.st.schedules) != 1 {
+				t.Error("an invalid schedule was stored")
+			}
+		})
+	}
+}
+
+func TestCreateSchedule(t *testing.T) {
+	api := newTestAPI(t)
+	path := "/medicines/" + api.medicine.ID + "/schedules"
+	body := `{"frequency": "weekly", "start_date": "2025-06-02", "times_per_day": 2, "times": ["20:00", "08:00"]}`
+
+	if code, msg := api.do(api.alice, "POST", path, body); code != http.StatusCreated {
+		t.Fatalf("got %d %q, want 201", code, msg)
+	}
+	if len(api.st.schedules) != 2 || len(api.st.times) != 2 {
+		t.Errorf("stored %d schedules and %d times, want 2 and 2", len(api.st.schedules), len(api.st.times))
+	}
+
+	archivedAt := time.Now()
+	m := api.st.medicines[api.medicine.ID]
+	m.ArchivedAt = &archivedAt
+	api.st.medicines[m.ID] = m
+	if code, _ := api.do(api.alice, "POST", path, body); code != http.StatusConflict {
+		t.Errorf("archived medicine: got %d, want 409", code)
+	}
+}
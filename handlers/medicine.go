@@ -2,74 +2,236 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"pillTickr-backend/db"
+	"pillTickr-backend/models"
+	"pillTickr-backend/store"
 	"pillTickr-backend/utils"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GET /medicines
-func GetMedicines(c *gin.Context) {
+// GET /medicines?include_archived=true
+func (h *Handler) GetMedicines(c *gin.Context) {
 	userID, ok := utils.GetUserID(c)
 	if !ok {
 		return
 	}
+	includeArchived := c.Query("include_archived") == "true"
 
-	rows, err := db.DB.Query(`SELECT medicine_id, name, description, dosage, instructions, created_at 
-		FROM medicines WHERE user_id = ?`, userID)
+	medicines, err := h.store.Medicines().ListMedicines(c.Request.Context(), userID, includeArchived)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medicines"})
 		return
 	}
-	defer rows.Close()
-
-	medicines := []gin.H{}
-	for rows.Next() {
-		var m struct {
-			ID           int    `json:"medicine_id"`
-			Name         string `json:"name"`
-			Description  string `json:"description"`
-			Dosage       string `json:"dosage"`
-			Instructions string `json:"instructions"`
-			CreatedAt    string `json:"created_at"`
-		}
-		if err := rows.Scan(&m.ID, &m.Name, &m.Description, &m.Dosage, &m.Instructions, &m.CreatedAt); err == nil {
-			medicines = append(medicines, gin.H{
-				"id": m.ID, "name": m.Name, "description": m.Description,
-				"dosage": m.Dosage, "instructions": m.Instructions, "created_at": m.CreatedAt,
-			})
-		}
-	}
 
 	c.JSON(http.StatusOK, medicines)
 }
 
 // POST /medicines
-func CreateMedicine(c *gin.Context) {
+// Schedules, each with their intake times, can be nested in the request. All
+// of it is validated first and stored in one transaction, so a failed request
+// never leaves a half-built medicine behind.
+func (h *Handler) CreateMedicine(c *gin.Context) {
 	userID, ok := utils.GetUserID(c)
 	if !ok {
 		return
 	}
 
 	var req struct {
-		Name         string `json:"name" binding:"required"`
-		Description  string `json:"description"`
-		Dosage       string `json:"dosage"`
-		Instructions string `json:"instructions"`
+		Name         string          `json:"name" binding:"required"`
+		Description  *string         `json:"description"`
+		Dosage       *string         `json:"dosage"`
+		Instructions *string         `json:"instructions"`
+		Schedules    []scheduleInput `json:"schedules" binding:"dive"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	res, err := db.DB.Exec(`INSERT INTO medicines (user_id, name, description, dosage, instructions)
-		VALUES (?, ?, ?, ?, ?)`, userID, req.Name, req.Description, req.Dosage, req.Instructions)
+	medicine := models.Medicine{
+		UserID:       userID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Dosage:       req.Dosage,
+		Instructions: req.Instructions,
+	}
+
+	drafts := make([]scheduleDraft, len(req.Schedules))
+	for i, in := range req.Schedules {
+		var msg string
+		if drafts[i], msg = in.toDraft(""); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("schedules[%d]: %s", i, msg)})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	created := make([]createdSchedule, len(drafts))
+	err := h.store.WithTx(ctx, func(tx store.Store) error {
+		if err := tx.Medicines().CreateMedicine(ctx, &medicine); err != nil {
+			return err
+		}
+		for i := range drafts {
+			drafts[i].schedule.MedicineID = medicine.ID
+			var err error
+			if created[i], err = createSchedule(ctx, tx, &drafts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create medicine", "error": err.Error()})
 		return
 	}
 
-	id, _ := res.LastInsertId()
-	c.JSON(http.StatusCreated, gin.H{"medicine_id": id})
+	for _, s := range created {
+		h.regenerateReminders(c, s.ScheduleID)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"medicine_id": medicine.ID, "schedules": created})
+}
+
+// GET /medicines/:id
+func (h *Handler) GetMedicine(c *gin.Context) {
+	medicine, ok := h.loadMedicine(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, medicine)
+}
+
+// PUT /medicines/:id
+// Replaces the medicine's details; optional fields left out are cleared. The
+// archived state only changes when "archived" is given.
+func (h *Handler) ReplaceMedicine(c *gin.Context) {
+	var req struct {
+		Name         string  `json:"name" binding:"required"`
+		Description  *string `json:"description"`
+		Dosage       *string `json:"dosage"`
+		Instructions *string `json:"instructions"`
+		Archived     *bool   `json:"archived"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A replacement clears what it leaves out, which a patch does with ""
+	patch := models.MedicinePatch{
+		Name:         &req.Name,
+		Description:  orEmpty(req.Description),
+		Dosage:       orEmpty(req.Dosage),
+		Instructions: orEmpty(req.Instructions),
+		Archived:     req.Archived,
+	}
+	h.applyMedicinePatch(c, patch)
+}
+
+// PATCH /medicines/:id
+// Updates only the fields given. {"archived": true} stops the medicine: its
+// future reminders are removed, while past ones stay for adherence reports.
+func (h *Handler) UpdateMedicine(c *gin.Context) {
+	var patch models.MedicinePatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.applyMedicinePatch(c, patch)
+}
+
+// DELETE /medicines/:id
+// Deletes the medicine with its schedules and whole reminder history; archive
+// it instead to keep the history.
+func (h *Handler) DeleteMedicine(c *gin.Context) {
+	err := h.store.Medicines().DeleteMedicine(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete medicine"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Medicine deleted"})
+}
+
+// loadMedicine fetches the medicine named by the :id parameter, writing the
+// error response itself when it cannot
+func (h *Handler) loadMedicine(c *gin.Context) (models.Medicine, bool) {
+	medicine, err := h.store.Medicines().GetMedicine(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return medicine, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medicine"})
+		return medicine, false
+	}
+	return medicine, true
+}
+
+// applyMedicinePatch saves a patched medicine. Archiving removes its future
+// reminders in the same transaction; restoring it generates them again.
+func (h *Handler) applyMedicinePatch(c *gin.Context, patch models.MedicinePatch) {
+	before, ok := h.loadMedicine(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	medicine := before
+	patch.Apply(&medicine, now)
+	if strings.TrimSpace(medicine.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.store.WithTx(ctx, func(tx store.Store) error {
+		if err := tx.Medicines().UpdateMedicine(ctx, medicine); err != nil {
+			return err
+		}
+		if medicine.Archived() && !before.Archived() {
+			return tx.Reminders().DeleteFutureMedicineReminders(ctx, medicine.ID, now)
+		}
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medicine"})
+		return
+	}
+
+	if before.Archived() && !medicine.Archived() {
+		schedules, err := h.store.Schedules().ListSchedules(ctx, medicine.ID)
+		if err != nil {
+			// The next generator run picks the schedules up again
+			slog.Error("Failed to list schedules of restored medicine", "medicine_id", medicine.ID, "error", err)
+		}
+		for _, s := range schedules {
+			h.regenerateReminders(c, s.ID)
+		}
+	}
+
+	c.JSON(http.StatusOK, medicine)
+}
+
+// orEmpty returns v, or a pointer to "" when v is nil
+func orEmpty(v *string) *string {
+	if v == nil {
+		return new(string)
+	}
+	return v
 }
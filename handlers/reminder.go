@@ -1,56 +1,39 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
+	"log/slog"
 	"net/http"
-	"pillTickr-backend/db"
+	"pillTickr-backend/authz"
 	"pillTickr-backend/models"
+	"pillTickr-backend/store"
 	"pillTickr-backend/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GetReminders(c *gin.Context) {
+func (h *Handler) GetReminders(c *gin.Context) {
 	userID, ok := utils.GetUserID(c)
 	if !ok {
 		return
 	}
 
-	rows, err := db.DB.Query(`
-		SELECT r.reminder_id, r.schedule_id, r.reminder_datetime, r.status, r.taken_at
-		FROM reminders r
-		INNER JOIN schedules s ON r.schedule_id = s.schedule_id
-		INNER JOIN medicines m ON s.medicine_id = m.medicine_id
-		WHERE m.user_id = ?`, userID)
+	reminders, err := h.store.Reminders().ListReminders(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		slog.Error("Failed to list reminders", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
 		return
 	}
-	defer rows.Close()
-
-	var reminders []models.Reminder
-	for rows.Next() {
-		var r models.Reminder
-		err := rows.Scan(&r.ID, &r.ScheduleID, &r.ReminderDatetime, &r.Status, &r.TakenAt)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		reminders = append(reminders, r)
-	}
 
 	c.JSON(http.StatusOK, reminders)
 }
 
-func CreateReminder(c *gin.Context) {
+func (h *Handler) CreateReminder(c *gin.Context) {
 	userID, ok := utils.GetUserID(c)
 	if !ok {
 		return
 	}
-	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
-		return
-	}
 
 	var reminder models.Reminder
 	if err := c.ShouldBindJSON(&reminder); err != nil {
@@ -64,62 +47,94 @@ func CreateReminder(c *gin.Context) {
 		return
 	}
 
+	// The schedule comes from the body, so ownership is checked here rather than by route
+	if err := authz.Authorize(c.Request.Context(), h.store, userID, authz.Schedule, reminder.ScheduleID); err != nil {
+		if errors.Is(err, authz.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return
+		}
+		slog.Error("Failed to authorize reminder schedule", "schedule_id", reminder.ScheduleID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reminder"})
+		return
+	}
+
 	reminder.Status = "pending"
+	reminder.TakenAt = nil // no taken_at yet
+	// Stored in UTC so it compares correctly with generated reminders
+	reminder.ReminderDatetime = reminder.ReminderDatetime.UTC()
 
-	_, err := db.DB.Exec(`
-		INSERT INTO reminders ( schedule_id, reminder_datetime, status, taken_at)
-		VALUES ( ?, ?, ?, ?)`,
-		reminder.ScheduleID,
-		reminder.ReminderDatetime,
-		reminder.Status,
-		sql.NullTime{Valid: false}, // no taken_at yet
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.store.Reminders().CreateReminder(c.Request.Context(), &reminder); err != nil {
+		slog.Error("Failed to create reminder", "schedule_id", reminder.ScheduleID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reminder"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, reminder)
 }
 
-func UpdateReminder(c *gin.Context) {
-	reminderID := c.Param("id")
+func (h *Handler) UpdateReminder(c *gin.Context) {
 	var reminder models.Reminder
 
 	if err := c.ShouldBindJSON(&reminder); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	reminder.ID = c.Param("id")
 
 	// Ensure valid time
 	if reminder.ReminderDatetime.IsZero() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Reminder datetime is required"})
 		return
 	}
+	reminder.ReminderDatetime = reminder.ReminderDatetime.UTC()
+	// The whole reminder is saved, so its status must be given
+	if reminder.Status == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
+		return
+	}
+
+	// Record when the dose was taken so it can be judged on time or late
+	if reminder.Status == "taken" && reminder.TakenAt == nil {
+		now := time.Now().UTC()
+		reminder.TakenAt = &now
+	}
 
-	_, err := db.DB.Exec(`
-		UPDATE reminders
-		SET reminder_datetime = ?, status = ?, taken_at = ?
-		WHERE id = ?`,
-		reminder.ReminderDatetime,
-		reminder.Status,
-		reminder.TakenAt,
-		reminderID,
-	)
+	err := h.store.Reminders().UpdateReminder(c.Request.Context(), reminder)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A reminder already exists for this schedule at that time"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		slog.Error("Failed to update reminder", "reminder_id", reminder.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reminder"})
 		return
 	}
 
+	// Interval doses are counted from the last dose taken, so the ones after
+	// this reminder move with it
+	if stored, err := h.store.Reminders().GetReminder(c.Request.Context(), reminder.ID); err == nil {
+		reminder = stored
+		if schedule, err := h.store.Schedules().GetSchedule(c.Request.Context(), stored.ScheduleID); err == nil && schedule.Frequency == "interval" {
+			h.regenerateReminders(c, stored.ScheduleID)
+		}
+	}
+
 	c.JSON(http.StatusOK, reminder)
 }
 
-func DeleteReminder(c *gin.Context) {
-	reminderID := c.Param("id")
-
-	_, err := db.DB.Exec(`DELETE FROM reminders WHERE id = ?`, reminderID)
+func (h *Handler) DeleteReminder(c *gin.Context) {
+	err := h.store.Reminders().DeleteReminder(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		slog.Error("Failed to delete reminder", "reminder_id", c.Param("id"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reminder"})
 		return
 	}
 
@@ -2,66 +2,533 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
-	"pillTickr-backend/db"
+	"pillTickr-backend/models"
+	"pillTickr-backend/scheduler"
+	"pillTickr-backend/store"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GET /medicines/:id/schedules
-func GetSchedules(c *gin.Context) {
+func (h *Handler) GetSchedules(c *gin.Context) {
 	medicineID := c.Param("id")
 
-	rows, err := db.DB.Query(`SELECT schedule_id, start_date, end_date, frequency, times_per_day
-		FROM schedules WHERE medicine_id = ?`, medicineID)
+	schedules, err := h.store.Schedules().ListSchedules(c.Request.Context(), medicineID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
 		return
 	}
-	defer rows.Close()
+	today, ok := h.userToday(c)
+	if !ok {
+		return
+	}
+	for i := range schedules {
+		if err := h.withScheduleState(c.Request.Context(), &schedules[i], today); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// scheduleInput is the body of POST /medicines/:id/schedules, and of each
+// schedule nested in POST /medicines
+type scheduleInput struct {
+	// StartDate may be left out of interval schedules, which then start on
+	// the date of their first dose
+	StartDate    string  `json:"start_date"`
+	EndDate      *string `json:"end_date"`
+	Frequency    string  `json:"frequency" binding:"required"`
+	TimesPerDay  int     `json:"times_per_day"`
+	GraceMinutes *int    `json:"grace_minutes" binding:"omitempty,min=0"`
+	// Weekdays (e.g. ["mon", "wed", "fri"]) are for weekly schedules; they
+	// default to the weekday of start_date
+	Weekdays models.Weekdays `json:"weekdays"`
+	// RRule (e.g. "FREQ=MONTHLY;BYDAY=2TU") defines custom schedules, which
+	// skip the days ("YYYY-MM-DD") in ExDates
+	RRule   string   `json:"rrule"`
+	ExDates []string `json:"exdates"`
+	// IntervalHours and FirstDoseAt define interval schedules ("every 8 hours")
+	IntervalHours int        `json:"interval_hours"`
+	FirstDoseAt   *time.Time `json:"first_dose_at"`
+	// CycleActiveDays and CycleRestDays define cyclic schedules ("21 days on, 7 off")
+	CycleActiveDays int `json:"cycle_active_days"`
+	CycleRestDays   int `json:"cycle_rest_days"`
+	// TotalDoses makes the schedule a fixed course ending after that many doses
+	TotalDoses int `json:"total_doses" binding:"omitempty,min=1"`
+	// Times optionally sets the intake times ("HH:MM") in the same request
+	Times []string `json:"times"`
+	// DoseSteps optionally sets the dose steps in the same request
+	DoseSteps []doseStepInput `json:"dose_steps" binding:"dive"`
+}
+
+// scheduleDraft is a validated schedule with the intake times and dose steps
+// to create along with it
+type scheduleDraft struct {
+	schedule    models.Schedule
+	intakeTimes []string
+	doseSteps   []models.DoseStep
+}
 
-	schedules := []gin.H{}
-	for rows.Next() {
-		var s struct {
-			ID          int     `json:"schedule_id"`
-			StartDate   string  `json:"start_date"`
-			EndDate     *string `json:"end_date"`
-			Frequency   string  `json:"frequency"`
-			TimesPerDay int     `json:"times_per_day"`
+// toDraft builds and validates the schedule described by the input, with its
+// normalized intake times and dose steps, or returns a message for the client
+func (in scheduleInput) toDraft(medicineID string) (scheduleDraft, string) {
+	schedule := models.Schedule{
+		MedicineID:      medicineID,
+		Frequency:       in.Frequency,
+		TimesPerDay:     in.TimesPerDay,
+		Weekdays:        in.Weekdays,
+		RRule:           in.RRule,
+		ExDates:         in.ExDates,
+		IntervalHours:   in.IntervalHours,
+		FirstDoseAt:     in.FirstDoseAt,
+		CycleActiveDays: in.CycleActiveDays,
+		CycleRestDays:   in.CycleRestDays,
+		TotalDoses:      in.TotalDoses,
+		GraceMinutes:    models.DefaultGraceMinutes,
+	}
+	if in.GraceMinutes != nil {
+		schedule.GraceMinutes = *in.GraceMinutes
+	}
+
+	switch {
+	case in.StartDate != "":
+		startDate, err := time.Parse("2006-01-02", in.StartDate)
+		if err != nil {
+			return scheduleDraft{}, "start_date must be a date in YYYY-MM-DD format"
 		}
-		if err := rows.Scan(&s.ID, &s.StartDate, &s.EndDate, &s.Frequency, &s.TimesPerDay); err == nil {
-			schedules = append(schedules, gin.H{
-				"id": s.ID, "start_date": s.StartDate, "end_date": s.EndDate,
-				"frequency": s.Frequency, "times_per_day": s.TimesPerDay,
-			})
+		schedule.StartDate = startDate
+	case in.Frequency == "interval" && in.FirstDoseAt != nil:
+		// The date as the client wrote it, in the offset it was given in
+		y, m, d := in.FirstDoseAt.Date()
+		schedule.StartDate = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case in.Frequency == "interval":
+		return scheduleDraft{}, "interval schedules need first_dose_at"
+	default:
+		return scheduleDraft{}, "start_date is required"
+	}
+	if in.EndDate != nil {
+		endDate, err := time.Parse("2006-01-02", *in.EndDate)
+		if err != nil {
+			return scheduleDraft{}, "end_date must be a date in YYYY-MM-DD format"
 		}
+		schedule.EndDate = &endDate
+	}
+	normalizeSchedule(&schedule)
+	if msg := validateSchedule(schedule); msg != "" {
+		return scheduleDraft{}, msg
 	}
 
-	c.JSON(http.StatusOK, schedules)
+	intakeTimes, msg := normalizeIntakeTimes(in.Times)
+	if msg != "" {
+		return scheduleDraft{}, msg
+	}
+	if schedule.Frequency == "interval" && len(intakeTimes) > 0 {
+		return scheduleDraft{}, "interval schedules take no intake times"
+	}
+	if len(intakeTimes) > schedule.TimesPerDay {
+		return scheduleDraft{}, "times has more entries than times_per_day"
+	}
+
+	doseSteps, msg := normalizeDoseSteps(in.DoseSteps)
+	if msg != "" {
+		return scheduleDraft{}, msg
+	}
+	return scheduleDraft{schedule: schedule, intakeTimes: intakeTimes, doseSteps: doseSteps}, ""
+}
+
+// createdSchedule reports the IDs given to a schedule and its intake times
+type createdSchedule struct {
+	ScheduleID string   `json:"schedule_id"`
+	TimeIDs    []string `json:"time_ids"`
+}
+
+// createSchedule stores a drafted schedule with its intake times and dose
+// steps, filling in the schedule's ID; callers run it in a transaction
+func createSchedule(ctx context.Context, tx store.Store, d *scheduleDraft) (createdSchedule, error) {
+	if err := tx.Schedules().CreateSchedule(ctx, &d.schedule); err != nil {
+		return createdSchedule{}, err
+	}
+	created := createdSchedule{ScheduleID: d.schedule.ID, TimeIDs: []string{}}
+	for _, intakeTime := range d.intakeTimes {
+		t := models.ScheduleTime{ScheduleID: d.schedule.ID, IntakeTime: intakeTime}
+		if err := tx.Schedules().CreateScheduleTime(ctx, &t); err != nil {
+			return created, err
+		}
+		created.TimeIDs = append(created.TimeIDs, t.ID)
+	}
+	if len(d.doseSteps) > 0 {
+		if _, err := tx.Schedules().ReplaceDoseSteps(ctx, d.schedule.ID, d.doseSteps); err != nil {
+			return created, err
+		}
+	}
+	_, err := tx.Schedules().RecordScheduleVersion(ctx, d.schedule.ID, time.Now())
+	return created, err
 }
 
 // POST /medicines/:id/schedules
-func CreateSchedule(c *gin.Context) {
+func (h *Handler) CreateSchedule(c *gin.Context) {
 	medicineID := c.Param("id")
 
+	var req scheduleInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	draft, msg := req.toDraft(medicineID)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	archived, err := h.store.Medicines().IsMedicineArchived(c.Request.Context(), medicineID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "Medicine is archived; restore it before adding schedules"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var created createdSchedule
+	err = h.store.WithTx(ctx, func(tx store.Store) error {
+		created, err = createSchedule(ctx, tx, &draft)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		return
+	}
+
+	h.regenerateReminders(c, created.ScheduleID)
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GET /schedules/:id
+func (h *Handler) GetSchedule(c *gin.Context) {
+	schedule, err := h.store.Schedules().GetSchedule(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+		return
+	}
+	today, ok := h.userToday(c)
+	if !ok {
+		return
+	}
+	if err := h.withScheduleState(c.Request.Context(), &schedule, today); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// PATCH /schedules/:id
+// Updates only the fields given; an empty end_date makes the schedule ongoing.
+// Future pending reminders are regenerated from the new definition.
+func (h *Handler) UpdateSchedule(c *gin.Context) {
+	scheduleID := c.Param("id")
+
 	var req struct {
-		StartDate   string  `json:"start_date" binding:"required"`
-		EndDate     *string `json:"end_date"`
-		Frequency   string  `json:"frequency" binding:"required"`
-		TimesPerDay int     `json:"times_per_day" binding:"required"`
+		StartDate       *string          `json:"start_date"`
+		EndDate         *string          `json:"end_date"`
+		Frequency       *string          `json:"frequency"`
+		TimesPerDay     *int             `json:"times_per_day"`
+		GraceMinutes    *int             `json:"grace_minutes" binding:"omitempty,min=0"`
+		Weekdays        *models.Weekdays `json:"weekdays"`
+		RRule           *string          `json:"rrule"`
+		ExDates         *[]string        `json:"exdates"`
+		IntervalHours   *int             `json:"interval_hours"`
+		FirstDoseAt     *time.Time       `json:"first_dose_at"`
+		CycleActiveDays *int             `json:"cycle_active_days"`
+		CycleRestDays   *int             `json:"cycle_rest_days"`
+		// 0 turns a fixed course back into an open-ended schedule
+		TotalDoses *int `json:"total_doses" binding:"omitempty,min=0"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	today, ok := h.userToday(c)
+	if !ok {
+		return
+	}
 
-	res, err := db.DB.Exec(`INSERT INTO schedules (medicine_id, start_date, end_date, frequency, times_per_day)
-		VALUES (?, ?, ?, ?, ?)`, medicineID, req.StartDate, req.EndDate, req.Frequency, req.TimesPerDay)
+	ctx := c.Request.Context()
+	schedule, err := h.store.Schedules().GetSchedule(ctx, scheduleID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
 		return
 	}
 
-	id, _ := res.LastInsertId()
-	c.JSON(http.StatusCreated, gin.H{"schedule_id": id})
+	if req.StartDate != nil {
+		startDate, err := time.Parse("2006-01-02", *req.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must be a date in YYYY-MM-DD format"})
+			return
+		}
+		schedule.StartDate = startDate
+	}
+	if req.EndDate != nil {
+		schedule.EndDate = nil
+		if *req.EndDate != "" {
+			endDate, err := time.Parse("2006-01-02", *req.EndDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must be a date in YYYY-MM-DD format"})
+				return
+			}
+			schedule.EndDate = &endDate
+		}
+	}
+	if req.Frequency != nil {
+		if *req.Frequency != schedule.Frequency {
+			// Weekdays and rules belong to the old frequency unless new ones are given
+			schedule.Weekdays = 0
+			schedule.RRule = ""
+			schedule.ExDates = nil
+			schedule.IntervalHours = 0
+			schedule.FirstDoseAt = nil
+			schedule.CycleActiveDays = 0
+			schedule.CycleRestDays = 0
+		}
+		schedule.Frequency = *req.Frequency
+	}
+	if req.Weekdays != nil {
+		schedule.Weekdays = *req.Weekdays
+	}
+	if req.RRule != nil {
+		schedule.RRule = *req.RRule
+	}
+	if req.ExDates != nil {
+		schedule.ExDates = *req.ExDates
+	}
+	if req.IntervalHours != nil {
+		schedule.IntervalHours = *req.IntervalHours
+	}
+	if req.FirstDoseAt != nil {
+		schedule.FirstDoseAt = req.FirstDoseAt
+	}
+	if req.CycleActiveDays != nil {
+		schedule.CycleActiveDays = *req.CycleActiveDays
+	}
+	if req.CycleRestDays != nil {
+		schedule.CycleRestDays = *req.CycleRestDays
+	}
+	if req.TotalDoses != nil {
+		schedule.TotalDoses = *req.TotalDoses
+	}
+	if req.TimesPerDay != nil {
+		schedule.TimesPerDay = *req.TimesPerDay
+	}
+	if req.GraceMinutes != nil {
+		schedule.GraceMinutes = *req.GraceMinutes
+	}
+	normalizeSchedule(&schedule)
+	if msg := validateSchedule(schedule); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	err = h.store.WithTx(ctx, func(tx store.Store) error {
+		times, err := tx.Schedules().ListScheduleTimes(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		if schedule.Frequency == "interval" && len(times) > 0 {
+			return errIntervalTimes
+		}
+		if len(times) > schedule.TimesPerDay {
+			return errTooManyTimes
+		}
+		if err := tx.Schedules().UpdateSchedule(ctx, schedule); err != nil {
+			return err
+		}
+		_, err = tx.Schedules().RecordScheduleVersion(ctx, scheduleID, time.Now())
+		return err
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	case errors.Is(err, errTooManyTimes):
+		c.JSON(http.StatusConflict, gin.H{"error": "The schedule has more intake times than times_per_day; remove some first"})
+		return
+	case errors.Is(err, errIntervalTimes):
+		c.JSON(http.StatusConflict, gin.H{"error": "Interval schedules have no intake times; remove them first"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedule"})
+		return
+	}
+
+	h.regenerateReminders(c, scheduleID)
+	// Read back for the counts derived from reminders, like remaining_doses
+	if stored, err := h.store.Schedules().GetSchedule(ctx, scheduleID); err == nil {
+		schedule = stored
+	}
+	if err := h.withScheduleState(ctx, &schedule, today); err != nil {
+		slog.Error("Failed to describe updated schedule", "schedule_id", scheduleID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DELETE /schedules/:id
+// Deletes the schedule with its times and all of its reminders.
+func (h *Handler) DeleteSchedule(c *gin.Context) {
+	err := h.store.Schedules().DeleteSchedule(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted"})
+}
+
+// scheduleFrequencies are the frequencies the schedules table accepts
+var scheduleFrequencies = map[string]bool{"daily": true, "weekly": true, "custom": true, "interval": true, "cyclic": true}
+
+// validateSchedule checks a schedule's definition, returning a message for
+// the client or "" if it is valid
+func validateSchedule(s models.Schedule) string {
+	if !scheduleFrequencies[s.Frequency] {
+		return "frequency must be one of daily, weekly, custom, interval or cyclic"
+	}
+	if s.TimesPerDay < 1 {
+		return "times_per_day must be at least 1"
+	}
+	if s.Weekdays != 0 && s.Frequency != "weekly" {
+		return "weekdays can only be set on weekly schedules"
+	}
+	if s.Frequency == "custom" {
+		if s.RRule == "" {
+			return "custom schedules need an rrule"
+		}
+		if _, err := scheduler.ParseRRule(s.RRule); err != nil {
+			return "rrule: " + err.Error()
+		}
+	} else if s.RRule != "" || len(s.ExDates) > 0 {
+		return "rrule and exdates can only be set on custom schedules"
+	}
+	if s.Frequency == "interval" {
+		if s.IntervalHours < 1 || s.IntervalHours > 168 {
+			return "interval schedules need interval_hours between 1 and 168"
+		}
+		if s.FirstDoseAt == nil {
+			return "interval schedules need first_dose_at"
+		}
+	} else if s.IntervalHours != 0 || s.FirstDoseAt != nil {
+		return "interval_hours and first_dose_at can only be set on interval schedules"
+	}
+	if s.Frequency == "cyclic" {
+		if s.CycleActiveDays < 1 || s.CycleActiveDays > 365 || s.CycleRestDays < 1 || s.CycleRestDays > 365 {
+			return "cyclic schedules need cycle_active_days and cycle_rest_days between 1 and 365"
+		}
+	} else if s.CycleActiveDays != 0 || s.CycleRestDays != 0 {
+		return "cycle_active_days and cycle_rest_days can only be set on cyclic schedules"
+	}
+	for _, d := range s.ExDates {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return "exdates must be dates in YYYY-MM-DD format"
+		}
+	}
+	if s.EndDate != nil && s.EndDate.Before(s.StartDate) {
+		return "end_date must not be before start_date"
+	}
+	return ""
+}
+
+// normalizeSchedule fills in defaults and puts values in their stored form
+// before validation. A weekly schedule without weekdays repeats on the weekday
+// it starts, as weekly schedules always did before weekdays existed.
+func normalizeSchedule(s *models.Schedule) {
+	if s.Frequency == "weekly" && s.Weekdays == 0 {
+		s.Weekdays = models.WeekdaysOf(s.StartDate.Weekday())
+	}
+	// Interval schedules have as many doses a day as their interval allows
+	if s.Frequency == "interval" && s.IntervalHours > 0 {
+		s.TimesPerDay = (24 + s.IntervalHours - 1) / s.IntervalHours
+	}
+
+	s.RRule = strings.ToUpper(strings.TrimSpace(s.RRule))
+	s.RRule = strings.TrimPrefix(s.RRule, "RRULE:")
+
+	if len(s.ExDates) > 0 {
+		exdates := make([]string, 0, len(s.ExDates))
+		for _, d := range s.ExDates {
+			exdates = append(exdates, strings.TrimSpace(d))
+		}
+		slices.Sort(exdates)
+		s.ExDates = slices.Compact(exdates)
+	} else {
+		s.ExDates = nil
+	}
+}
+
+// withScheduleState fills in the fields describing where a schedule stands
+// today, the current time in its owner's zone: its cycle day, its current
+// and next dose steps and the pause it is in
+func (h *Handler) withScheduleState(ctx context.Context, s *models.Schedule, today time.Time) error {
+	withCycleDay(s, today)
+
+	steps, err := h.store.Schedules().ListDoseSteps(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+	if i := scheduler.StepOn(steps, today); i >= 0 {
+		s.CurrentStep = &steps[i]
+	}
+	if i := scheduler.NextStep(steps, today); i >= 0 {
+		s.NextStep = &steps[i]
+	}
+
+	pauses, err := h.store.Schedules().ListPauses(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+	if i := scheduler.PauseAt(pauses, today); i >= 0 {
+		s.Pause = &pauses[i]
+	}
+	return nil
+}
+
+// withCycleDay fills in where a cyclic schedule is in its cycle today; it is
+// left empty for other schedules and outside the schedule's dates
+func withCycleDay(s *models.Schedule, today time.Time) {
+	if s.EndDate != nil && today.Format("2006-01-02") > s.EndDate.Format("2006-01-02") {
+		return
+	}
+	day := scheduler.CycleDay(*s, today)
+	if day == 0 {
+		return
+	}
+	s.CycleDay = day
+	s.CyclePhase = "active"
+	if day > s.CycleActiveDays {
+		s.CyclePhase = "rest"
+	}
 }
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"pillTickr-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /schedules/:id/pauses
+func (h *Handler) GetSchedulePauses(c *gin.Context) {
+	pauses, err := h.store.Schedules().ListPauses(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pauses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pauses)
+}
+
+// POST /schedules/:id/pause
+// Puts a schedule on hold from now, e.g. before surgery. With resume_on it
+// resumes by itself at the start of that day. Future pending reminders are
+// removed; doses not taken while paused do not count against adherence.
+func (h *Handler) PauseSchedule(c *gin.Context) {
+	scheduleID := c.Param("id")
+
+	var req struct {
+		ResumeOn *string `json:"resume_on"` // YYYY-MM-DD; omitted = until resumed
+	}
+	// The body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	today, ok := h.userToday(c)
+	if !ok {
+		return
+	}
+	var resumeAt *time.Time
+	if req.ResumeOn != nil {
+		day, err := time.ParseInLocation("2006-01-02", *req.ResumeOn, today.Location())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "resume_on must be a date in YYYY-MM-DD format"})
+			return
+		}
+		if !day.After(today) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "resume_on must be after today"})
+			return
+		}
+		// UTC like the pause read back from the store
+		day = day.UTC()
+		resumeAt = &day
+	}
+
+	pause, err := h.store.Schedules().PauseSchedule(c.Request.Context(), scheduleID, time.Now(), resumeAt)
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Schedule is already paused"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pause schedule"})
+		return
+	}
+
+	h.regenerateReminders(c, scheduleID)
+
+	c.JSON(http.StatusOK, pause)
+}
+
+// POST /schedules/:id/resume
+// Ends the pause now and generates reminders again from here on.
+func (h *Handler) ResumeSchedule(c *gin.Context) {
+	scheduleID := c.Param("id")
+
+	pause, err := h.store.Schedules().ResumeSchedule(c.Request.Context(), scheduleID, time.Now())
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Schedule is not paused"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume schedule"})
+		return
+	}
+
+	h.regenerateReminders(c, scheduleID)
+
+	c.JSON(http.StatusOK, pause)
+}
@@ -2,14 +2,66 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
-	"pillTickr-backend/db"
+	"pillTickr-backend/models"
+	"pillTickr-backend/scheduler"
+	"pillTickr-backend/store"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+var (
+	// errTooManyTimes means a schedule would get more intake times than its times_per_day
+	errTooManyTimes = errors.New("too many intake times")
+	// errIntervalTimes means intake times were given to an interval schedule
+	errIntervalTimes = errors.New("interval schedules have no intake times")
+)
+
+// normalizeIntakeTime validates an "HH:MM" intake time and writes it zero-padded,
+// so the same clock time is always stored the same way
+func normalizeIntakeTime(value string) (string, error) {
+	hour, minute, err := scheduler.ParseIntakeTime(value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%02d:%02d", hour, minute), nil
+}
+
+// normalizeIntakeTimes normalizes a set of intake times, returning a message
+// for the client if one is invalid or given twice
+func normalizeIntakeTimes(values []string) ([]string, string) {
+	intakeTimes := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		intakeTime, err := normalizeIntakeTime(value)
+		if err != nil {
+			return nil, err.Error()
+		}
+		if seen[intakeTime] {
+			return nil, fmt.Sprintf("intake time %s is given twice", intakeTime)
+		}
+		seen[intakeTime] = true
+		intakeTimes = append(intakeTimes, intakeTime)
+	}
+	return intakeTimes, ""
+}
+
+// GET /schedules/:id/times
+func (h *Handler) GetScheduleTimes(c *gin.Context) {
+	times, err := h.store.Schedules().ListScheduleTimes(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule times"})
+		return
+	}
+
+	c.JSON(http.StatusOK, times)
+}
+
 // POST /schedules/:id/times
-func AddScheduleTime(c *gin.Context) {
+func (h *Handler) AddScheduleTime(c *gin.Context) {
 	scheduleID := c.Param("id")
 
 	var req struct {
@@ -20,12 +72,145 @@ func AddScheduleTime(c *gin.Context) {
 		return
 	}
 
-	res, err := db.DB.Exec(`INSERT INTO schedule_times (schedule_id, intake_time) VALUES (?, ?)`, scheduleID, req.IntakeTime)
+	intakeTime, err := normalizeIntakeTime(req.IntakeTime)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	scheduleTime := models.ScheduleTime{ScheduleID: scheduleID, IntakeTime: intakeTime}
+	err = h.store.WithTx(ctx, func(tx store.Store) error {
+		schedule, err := tx.Schedules().GetSchedule(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		times, err := tx.Schedules().ListScheduleTimes(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		if schedule.Frequency == "interval" {
+			return errIntervalTimes
+		}
+		for _, t := range times {
+			if t.IntakeTime == intakeTime {
+				return store.ErrConflict
+			}
+		}
+		if len(times) >= schedule.TimesPerDay {
+			return errTooManyTimes
+		}
+		if err := tx.Schedules().CreateScheduleTime(ctx, &scheduleTime); err != nil {
+			return err
+		}
+		_, err = tx.Schedules().RecordScheduleVersion(ctx, scheduleID, time.Now())
+		return err
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "The schedule already has this intake time"})
+		return
+	case errors.Is(err, errTooManyTimes):
+		c.JSON(http.StatusConflict, gin.H{"error": "The schedule already has times_per_day intake times"})
+		return
+	case errors.Is(err, errIntervalTimes):
+		c.JSON(http.StatusConflict, gin.H{"error": "Interval schedules have no intake times"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add schedule time"})
 		return
 	}
 
-	id, _ := res.LastInsertId()
-	c.JSON(http.StatusCreated, gin.H{"time_id": id})
+	h.regenerateReminders(c, scheduleID)
+
+	c.JSON(http.StatusCreated, gin.H{"time_id": scheduleTime.ID})
+}
+
+// PUT /schedules/:id/times
+// Replaces the schedule's whole set of intake times. Future pending reminders
+// are regenerated from the new set; past and acted-on reminders are kept.
+func (h *Handler) ReplaceScheduleTimes(c *gin.Context) {
+	scheduleID := c.Param("id")
+
+	var req struct {
+		IntakeTimes []string `json:"intake_times" binding:"required"` // "HH:MM"
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	intakeTimes, msg := normalizeIntakeTimes(req.IntakeTimes)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var times []models.ScheduleTime
+	err := h.store.WithTx(ctx, func(tx store.Store) error {
+		schedule, err := tx.Schedules().GetSchedule(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		if schedule.Frequency == "interval" && len(intakeTimes) > 0 {
+			return errIntervalTimes
+		}
+		if len(intakeTimes) > schedule.TimesPerDay {
+			return errTooManyTimes
+		}
+		if times, err = tx.Schedules().ReplaceScheduleTimes(ctx, scheduleID, intakeTimes); err != nil {
+			return err
+		}
+		_, err = tx.Schedules().RecordScheduleVersion(ctx, scheduleID, time.Now())
+		return err
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	case errors.Is(err, errTooManyTimes):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "More intake times than the schedule's times_per_day"})
+		return
+	case errors.Is(err, errIntervalTimes):
+		c.JSON(http.StatusConflict, gin.H{"error": "Interval schedules have no intake times"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace schedule times"})
+		return
+	}
+
+	h.regenerateReminders(c, scheduleID)
+
+	c.JSON(http.StatusOK, times)
+}
+
+// DELETE /schedules/:id/times/:timeId
+func (h *Handler) DeleteScheduleTime(c *gin.Context) {
+	scheduleID := c.Param("id")
+
+	ctx := c.Request.Context()
+	err := h.store.WithTx(ctx, func(tx store.Store) error {
+		if err := tx.Schedules().DeleteScheduleTime(ctx, scheduleID, c.Param("timeId")); err != nil {
+			return err
+		}
+		_, err := tx.Schedules().RecordScheduleVersion(ctx, scheduleID, time.Now())
+		return err
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule time"})
+		return
+	}
+
+	// Drops the pending reminders the time had already generated
+	h.regenerateReminders(c, scheduleID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule time deleted"})
 }
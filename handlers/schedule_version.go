@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"pillTickr-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scheduleHistoryEntry is one version of a schedule as its history shows it
+type scheduleHistoryEntry struct {
+	models.ScheduleVersion
+	// EffectiveUntil is when the next version took over; nil for the current one
+	EffectiveUntil *time.Time `json:"effective_until,omitempty"`
+	// Changes are the definition fields changed from the version before
+	Changes []string `json:"changes,omitempty"`
+}
+
+// GET /schedules/:id/history
+// Lists every version of the schedule, oldest first. Reminders carry the
+// version_id of the version they were generated from.
+func (h *Handler) GetScheduleHistory(c *gin.Context) {
+	versions, err := h.store.Schedules().ListScheduleVersions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule history"})
+		return
+	}
+
+	history := make([]scheduleHistoryEntry, len(versions))
+	for i, v := range versions {
+		history[i].ScheduleVersion = v
+		if i > 0 {
+			history[i].Changes = v.Definition.Changes(versions[i-1].Definition)
+			history[i-1].EffectiveUntil = v.EffectiveFrom
+		}
+	}
+
+	c.JSON(http.StatusOK, history)
+}
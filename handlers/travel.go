@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"pillTickr-backend/models"
+	"pillTickr-backend/store"
+	"pillTickr-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TravelPlanInput struct {
+	TimeZone  string `json:"timeZone" binding:"required"`  // IANA name of the destination
+	StartDate string `json:"startDate" binding:"required"` // YYYY-MM-DD, first day away
+	EndDate   string `json:"endDate" binding:"required"`   // YYYY-MM-DD, last day away
+	Policy    string `json:"policy" binding:"required,oneof=home local"`
+}
+
+// GET /users/me/travel
+func (h *Handler) GetTravelPlan(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.store.Users().GetTravelPlan(c.Request.Context(), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch travel plan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// PUT /users/me/travel
+// Sets the user's trip, replacing any earlier one, and regenerates future
+// reminders: with policy "local" the days away follow the destination's wall
+// clock, with "home" doses stay at the same instants as at home. Days after
+// the trip follow the home zone again.
+func (h *Handler) SetTravelPlan(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		return
+	}
+
+	var input TravelPlanInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := models.LoadTimeZone(input.TimeZone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeZone must be an IANA time zone such as Europe/Berlin"})
+		return
+	}
+	startDate, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "startDate must be a date in YYYY-MM-DD format"})
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", input.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endDate must be a date in YYYY-MM-DD format"})
+		return
+	}
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endDate must not be before startDate"})
+		return
+	}
+
+	today, ok := h.userToday(c)
+	if !ok {
+		return
+	}
+	if endDate.Format("2006-01-02") < today.Format("2006-01-02") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endDate has already passed"})
+		return
+	}
+
+	plan := models.TravelPlan{
+		UserID:    userID,
+		TimeZone:  input.TimeZone,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Policy:    input.Policy,
+	}
+	if err := h.store.Users().SetTravelPlan(c.Request.Context(), plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save travel plan"})
+		return
+	}
+
+	h.regenerateUserReminders(c, userID)
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// DELETE /users/me/travel
+// Cancels the trip; future reminders go back to the home zone.
+func (h *Handler) DeleteTravelPlan(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.store.Users().DeleteTravelPlan(c.Request.Context(), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete travel plan"})
+		return
+	}
+
+	h.regenerateUserReminders(c, userID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Travel plan deleted"})
+}
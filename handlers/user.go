@@ -0,0 +1,139 @@
+// handlers/user.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"pillTickr-backend/models"
+	"pillTickr-backend/store"
+	"pillTickr-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type UpdateProfileInput struct {
+	Name *string `json:"name" binding:"omitempty,min=1"`
+	// TimeZone is an IANA name such as "Europe/Berlin"; "" goes back to the server's zone
+	TimeZone *string `json:"timeZone"`
+}
+
+// GET /users/me
+func (h *Handler) GetProfile(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.store.Users().GetUser(c.Request.Context(), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// PATCH /users/me
+// Changing the time zone regenerates future reminders, so intake times stay
+// at the same hour on the user's new local clock.
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.TimeZone != nil && *input.TimeZone != "" {
+		if _, err := models.LoadTimeZone(*input.TimeZone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timeZone must be an IANA time zone such as Europe/Berlin"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.store.Users().GetUser(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	previousZone := user.TimeZone
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.TimeZone != nil {
+		user.TimeZone = *input.TimeZone
+	}
+	if err := h.store.Users().UpdateUser(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	if user.TimeZone != previousZone {
+		h.regenerateUserReminders(c, userID)
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type DeleteAccountInput struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DELETE /users/me
+// Deletes the account and all its data. The user's data key is destroyed with
+// it, so copies of their encrypted rows, such as in backups, become unreadable.
+func (h *Handler) DeleteAccount(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		return
+	}
+
+	var input DeleteAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.store.Users().GetUser(c.Request.Context(), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	// A stolen access token alone must not be enough to erase an account
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	err = h.store.Users().DeleteUser(c.Request.Context(), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
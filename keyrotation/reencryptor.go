@@ -0,0 +1,82 @@
+package keyrotation
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"pillTickr-backend/store"
+)
+
+const (
+	DefaultInterval  = time.Hour
+	defaultBatchSize = 100
+)
+
+// Reencryptor re-wraps users' data keys with the active master key after a
+// rotation, and moves medicine rows that are plaintext or still under a master
+// key onto their owner's data key, so retired keys can eventually be dropped
+type Reencryptor struct {
+	store     store.Store
+	interval  time.Duration
+	batchSize int
+}
+
+// NewReencryptor creates a re-encryption job that runs every interval
+func NewReencryptor(st store.Store, interval time.Duration) *Reencryptor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Reencryptor{
+		store:     st,
+		interval:  interval,
+		batchSize: defaultBatchSize,
+	}
+}
+
+// Run re-encrypts immediately and then on every tick until ctx is done
+func (r *Reencryptor) Run(ctx context.Context) {
+	slog.Info("Re-encryption job started", "interval", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.ReencryptAll(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Re-encryption failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Re-encryption job stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReencryptAll works through every stale row in batches and returns how many were re-encrypted
+func (r *Reencryptor) ReencryptAll(ctx context.Context) (int, error) {
+	total := 0
+	// Keys first: medicine rows are encrypted with data keys, which must be readable
+	for _, batch := range []func(context.Context, int) (int, error){
+		r.store.Users().RewrapUserKeys,
+		r.store.Medicines().ReencryptMedicines,
+	} {
+		for ctx.Err() == nil {
+			changed, err := batch(ctx, r.batchSize)
+			total += changed
+			if err != nil {
+				return total, err
+			}
+			if changed == 0 {
+				break
+			}
+		}
+	}
+
+	if total > 0 {
+		slog.Info("Rows re-encrypted with the active key", "count", total)
+	}
+	return total, ctx.Err()
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"pillTickr-backend/authz"
+	"pillTickr-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireOwnership aborts with 404 unless the authenticated user owns the
+// resource named by the given path parameter, as resolved by owners. It must
+// run after RequireAuth.
+func RequireOwnership(owners authz.OwnerResolver, resource authz.Resource, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := utils.GetUserID(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		err := authz.Authorize(c.Request.Context(), owners, userID, resource, c.Param(param))
+		if errors.Is(err, authz.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+			return
+		}
+		if err != nil {
+			slog.Error("Ownership check failed", "resource", resource, "id", c.Param(param), "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authorize request"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// DoseStep = the amount per dose of a schedule over a range of days, so a
+// schedule can taper or titrate
+type DoseStep struct {
+	ID         string     `json:"id"`          // UUID
+	ScheduleID string     `json:"schedule_id"` // FK to schedules
+	StartDate  time.Time  `json:"start_date"`
+	EndDate    *time.Time `json:"end_date,omitempty"` // nil = until the next step, ongoing for the last
+	Dose       string     `json:"dose"`               // e.g. "40 mg"
+}
@@ -1,13 +1,64 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Medicine struct {
-	ID           string    `json:"id"`      // UUID
-	UserID       string    `json:"user_id"` // FK to users
-	Name         string    `json:"name"`
-	Description  *string   `json:"description,omitempty"`
-	Dosage       *string   `json:"dosage,omitempty"`       // e.g. "1 pill"
-	Instructions *string   `json:"instructions,omitempty"` // e.g. "after meals"
-	CreatedAt    time.Time `json:"created_at"`
+	ID           string     `json:"id"`      // UUID
+	UserID       string     `json:"user_id"` // FK to users
+	Name         string     `json:"name"`
+	Description  *string    `json:"description,omitempty"`
+	Dosage       *string    `json:"dosage,omitempty"`       // e.g. "1 pill"
+	Instructions *string    `json:"instructions,omitempty"` // e.g. "after meals"
+	CreatedAt    time.Time  `json:"created_at"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"` // set once the medicine is no longer taken
+}
+
+// Archived reports whether the medicine was stopped; it keeps its history but gets no new reminders
+func (m Medicine) Archived() bool {
+	return m.ArchivedAt != nil
+}
+
+// MedicinePatch is a partial update of a medicine. Nil fields are left as they
+// are; an empty string clears an optional field.
+type MedicinePatch struct {
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	Dosage       *string `json:"dosage"`
+	Instructions *string `json:"instructions"`
+	Archived     *bool   `json:"archived"`
+}
+
+// Apply copies the fields set in p onto m. Archiving stamps now; archiving an
+// already archived medicine keeps its original time.
+func (p MedicinePatch) Apply(m *Medicine, now time.Time) {
+	if p.Name != nil {
+		m.Name = strings.TrimSpace(*p.Name)
+	}
+	patchOptional(&m.Description, p.Description)
+	patchOptional(&m.Dosage, p.Dosage)
+	patchOptional(&m.Instructions, p.Instructions)
+	if p.Archived != nil {
+		switch {
+		case *p.Archived && m.ArchivedAt == nil:
+			at := now.UTC()
+			m.ArchivedAt = &at
+		case !*p.Archived:
+			m.ArchivedAt = nil
+		}
+	}
+}
+
+// patchOptional sets an optional field from a patch value, clearing it for ""
+func patchOptional(field **string, v *string) {
+	switch {
+	case v == nil:
+	case *v == "":
+		*field = nil
+	default:
+		value := *v
+		*field = &value
+	}
 }
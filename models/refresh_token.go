@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RefreshToken is a stored refresh token; only the hash of the raw token is kept
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string // shared by every token rotated from the same login
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time // set when rotated
+	RevokedAt *time.Time // set on logout or reuse detection
+}
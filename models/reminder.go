@@ -4,9 +4,39 @@ import "time"
 
 // Reminder = an actual reminder instance generated for a schedule
 type Reminder struct {
-	ID               string     `json:"id"`                // UUID
-	ScheduleID       string     `json:"schedule_id"`       // FK to schedules
-	ReminderDatetime time.Time  `json:"reminder_datetime"` // exact datetime to remind
-	Status           string     `json:"status"`            // pending | taken | missed
+	ID               string     `json:"id"`                                                    // UUID
+	ScheduleID       string     `json:"schedule_id"`                                           // FK to schedules
+	TimeID           *string    `json:"time_id,omitempty"`                                     // FK to schedule_times for generated reminders
+	ReminderDatetime time.Time  `json:"reminder_datetime"`                                     // exact datetime to remind
+	Status           string     `json:"status" binding:"omitempty,oneof=pending taken missed"` // pending | taken | missed
 	TakenAt          *time.Time `json:"taken_at,omitempty"`
+	Source           string     `json:"source,omitempty"`     // manual | generated
+	Dose             *string    `json:"dose,omitempty"`       // amount due, from the schedule's dose step
+	VersionID        *string    `json:"version_id,omitempty"` // schedule version it was generated from
+	Outcome          string     `json:"outcome,omitempty"`    // pending | on_time | late | missed
+}
+
+// Outcomes of a reminder once its grace window is taken into account
+const (
+	OutcomePending = "pending"
+	OutcomeOnTime  = "on_time"
+	OutcomeLate    = "late"
+	OutcomeMissed  = "missed"
+)
+
+// ReminderOutcome classifies a reminder: a dose taken within grace of its due
+// time is on time, one taken after it is late. A taken dose without taken_at
+// is assumed on time because there is nothing to measure it against.
+func ReminderOutcome(status string, due time.Time, takenAt *time.Time, grace time.Duration) string {
+	switch status {
+	case "taken":
+		if takenAt != nil && takenAt.After(due.Add(grace)) {
+			return OutcomeLate
+		}
+		return OutcomeOnTime
+	case "missed":
+		return OutcomeMissed
+	default:
+		return OutcomePending
+	}
 }
@@ -2,11 +2,47 @@ package models
 
 import "time"
 
+// DefaultGraceMinutes matches the schedules.grace_minutes column default
+const DefaultGraceMinutes = 120
+
 type Schedule struct {
 	ID          string     `json:"id"`          // UUID
 	MedicineID  string     `json:"medicine_id"` // FK to medicines
 	StartDate   time.Time  `json:"start_date"`
 	EndDate     *time.Time `json:"end_date,omitempty"`
-	Frequency   string     `json:"frequency"` // daily | weekly | custom
+	Frequency   string     `json:"frequency"` // daily | weekly | custom | interval | cyclic
 	TimesPerDay int        `json:"times_per_day"`
+	// Weekdays are the days a weekly schedule runs on; empty for other frequencies
+	Weekdays Weekdays `json:"weekdays,omitempty"`
+	// RRule defines the days of a custom schedule as an RFC 5545 RRULE value,
+	// e.g. "FREQ=MONTHLY;BYDAY=2TU"; empty for other frequencies
+	RRule string `json:"rrule,omitempty"`
+	// ExDates are days ("YYYY-MM-DD") a custom schedule skips
+	ExDates []string `json:"exdates,omitempty"`
+	// IntervalHours is the time between doses of an interval schedule, which
+	// has no intake times; doses follow the last one taken, or FirstDoseAt
+	IntervalHours int        `json:"interval_hours,omitempty"`
+	FirstDoseAt   *time.Time `json:"first_dose_at,omitempty"`
+	// CycleActiveDays days with doses alternate with CycleRestDays days
+	// without on a cyclic schedule, starting from StartDate
+	CycleActiveDays int `json:"cycle_active_days,omitempty"`
+	CycleRestDays   int `json:"cycle_rest_days,omitempty"`
+	// CycleDay is today's 1-based day in the cycle and CyclePhase whether it
+	// is an "active" or "rest" day; reported by the API, never stored
+	CycleDay   int    `json:"cycle_day,omitempty"`
+	CyclePhase string `json:"cycle_phase,omitempty"`
+	// TotalDoses ends a fixed course after that many doses; 0 when open-ended.
+	// RemainingDoses is how many of them are still to be taken.
+	TotalDoses     int  `json:"total_doses,omitempty"`
+	RemainingDoses *int `json:"remaining_doses,omitempty"`
+	// CurrentStep and NextStep are the dose steps due today and after it;
+	// reported by the API, never stored
+	CurrentStep *DoseStep `json:"current_step,omitempty"`
+	NextStep    *DoseStep `json:"next_step,omitempty"`
+	// Pause is the pause in effect now, if any; reported by the API, never stored
+	Pause *SchedulePause `json:"pause,omitempty"`
+	// GraceMinutes is how long after a reminder a dose still counts as on time
+	GraceMinutes int `json:"grace_minutes"`
+	// GeneratedUntil is how far ahead reminders have been materialized
+	GeneratedUntil *time.Time `json:"-"`
 }
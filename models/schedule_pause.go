@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SchedulePause = a period a schedule is on hold, e.g. a blood thinner
+// stopped before surgery. No reminders are due while it lasts.
+type SchedulePause struct {
+	ID         string     `json:"id"`          // UUID
+	ScheduleID string     `json:"schedule_id"` // FK to schedules
+	PausedAt   time.Time  `json:"paused_at"`
+	ResumedAt  *time.Time `json:"resumed_at,omitempty"` // nil = until resumed; ahead of now when it resumes by itself
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ScheduleVersion = a schedule's definition as it stood from EffectiveFrom
+// until the next version took over. Reminders link to the version that
+// generated them, so edits never change what past reminders were based on.
+type ScheduleVersion struct {
+	ID         string `json:"id"`          // UUID
+	ScheduleID string `json:"schedule_id"` // FK to schedules
+	Version    int    `json:"version"`     // 1 for the schedule as created
+	// EffectiveFrom is when the version replaced the one before; nil for
+	// version 1, which applies from the schedule's start
+	EffectiveFrom *time.Time         `json:"effective_from"`
+	Definition    ScheduleDefinition `json:"definition"`
+	Reminders     int                `json:"reminders"` // reminders generated from this version
+}
+
+// ScheduleDefinition is what decides when a schedule's doses are due
+type ScheduleDefinition struct {
+	StartDate       time.Time  `json:"start_date"`
+	EndDate         *time.Time `json:"end_date,omitempty"`
+	Frequency       string     `json:"frequency"`
+	TimesPerDay     int        `json:"times_per_day"`
+	Weekdays        Weekdays   `json:"weekdays,omitempty"`
+	RRule           string     `json:"rrule,omitempty"`
+	ExDates         []string   `json:"exdates,omitempty"`
+	IntervalHours   int        `json:"interval_hours,omitempty"`
+	FirstDoseAt     *time.Time `json:"first_dose_at,omitempty"`
+	CycleActiveDays int        `json:"cycle_active_days,omitempty"`
+	CycleRestDays   int        `json:"cycle_rest_days,omitempty"`
+	TotalDoses      int        `json:"total_doses,omitempty"`
+	GraceMinutes    int        `json:"grace_minutes"`
+	IntakeTimes     []string   `json:"intake_times"` // "HH:MM", in order
+}
+
+// Definition returns the part of a schedule and its intake times that
+// decides when doses are due
+func (s Schedule) Definition(times []ScheduleTime) ScheduleDefinition {
+	intakeTimes := make([]string, 0, len(times))
+	for _, t := range times {
+		intakeTimes = append(intakeTimes, t.IntakeTime)
+	}
+	sort.Strings(intakeTimes)
+	return ScheduleDefinition{
+		StartDate:       s.StartDate,
+		EndDate:         s.EndDate,
+		Frequency:       s.Frequency,
+		TimesPerDay:     s.TimesPerDay,
+		Weekdays:        s.Weekdays,
+		RRule:           s.RRule,
+		ExDates:         s.ExDates,
+		IntervalHours:   s.IntervalHours,
+		FirstDoseAt:     s.FirstDoseAt,
+		CycleActiveDays: s.CycleActiveDays,
+		CycleRestDays:   s.CycleRestDays,
+		TotalDoses:      s.TotalDoses,
+		GraceMinutes:    s.GraceMinutes,
+		IntakeTimes:     intakeTimes,
+	}
+}
+
+// Changes lists the fields, by their JSON names, that differ from prev
+func (d ScheduleDefinition) Changes(prev ScheduleDefinition) []string {
+	cur, old := d.fields(), prev.fields()
+	var changed []string
+	for name := range cur {
+		if !reflect.DeepEqual(cur[name], old[name]) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// fields returns the definition as it is serialized, so values compare the
+// way clients see them rather than by internals such as nil versus empty slices
+func (d ScheduleDefinition) fields() map[string]any {
+	fields := map[string]any{}
+	if b, err := json.Marshal(d); err == nil {
+		_ = json.Unmarshal(b, &fields)
+	}
+	return fields
+}
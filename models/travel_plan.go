@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Travel policies: how intake times follow a user to another time zone
+const (
+	// TravelKeepHome keeps doses at the same instants as at home, so the
+	// hours between them do not change (e.g. for insulin)
+	TravelKeepHome = "home"
+	// TravelAdoptLocal moves doses to the same wall-clock times at the destination
+	TravelAdoptLocal = "local"
+)
+
+// TravelPlan = a user's trip to another time zone
+type TravelPlan struct {
+	UserID    string    `json:"-"`
+	TimeZone  string    `json:"timeZone"` // IANA name of the destination
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"` // last day away; the home zone applies again after it
+	Policy    string    `json:"policy"`  // "home" | "local"
+}
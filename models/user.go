@@ -0,0 +1,39 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+type User struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"` // bcrypt hash, never serialized
+	CreatedAt    time.Time `json:"created_at"`
+	// TimeZone is the IANA zone (e.g. "Europe/Berlin") the user's intake
+	// times and dates are in; empty means the server's zone
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// LoadTimeZone loads an IANA time zone chosen by a user. "Local" is refused
+// because it names whatever zone the server happens to run in.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, errors.New("time zone must be an IANA name such as Europe/Berlin")
+	}
+	return time.LoadLocation(name)
+}
+
+// Location returns the user's time zone, or fallback if they have none or it
+// can no longer be loaded
+func (u User) Location(fallback *time.Location) *time.Location {
+	if u.TimeZone == "" {
+		return fallback
+	}
+	loc, err := LoadTimeZone(u.TimeZone)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
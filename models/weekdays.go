@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Weekdays is a set of days of the week, stored as a bitmask with bit n set
+// for time.Weekday(n). In JSON it is a list of names such as ["mon", "fri"].
+type Weekdays uint8
+
+var weekdayNames = [7]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// WeekdaysOf returns the set holding the given days
+func WeekdaysOf(days ...time.Weekday) Weekdays {
+	var w Weekdays
+	for _, d := range days {
+		w |= 1 << d
+	}
+	return w
+}
+
+// Has reports whether d is in the set
+func (w Weekdays) Has(d time.Weekday) bool {
+	return w&(1<<d) != 0
+}
+
+// ParseWeekday parses a day name, either abbreviated ("mon") or in full ("Monday")
+func ParseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d, short := range weekdayNames {
+		if name == short || name == strings.ToLower(time.Weekday(d).String()) {
+			return time.Weekday(d), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q, expected one of sun, mon, tue, wed, thu, fri, sat", name)
+}
+
+// Names lists the days in the set from Sunday on
+func (w Weekdays) Names() []string {
+	names := []string{}
+	for d, name := range weekdayNames {
+		if w.Has(time.Weekday(d)) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (w Weekdays) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.Names())
+}
+
+func (w *Weekdays) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("weekdays must be a list of day names: %w", err)
+	}
+	*w = 0
+	for _, name := range names {
+		d, err := ParseWeekday(name)
+		if err != nil {
+			return err
+		}
+		*w |= WeekdaysOf(d)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"pillTickr-backend/store"
+)
+
+const (
+	DefaultInterval = 30 * time.Second
+	// DefaultMaxAge is how late a reminder may still be notified, so one
+	// missed during downtime is not sent hours afterwards
+	DefaultMaxAge      = 2 * time.Hour
+	defaultBatchSize   = 100
+	defaultMaxAttempts = 5
+	// claimLease is how long a claimed reminder is reserved for one dispatcher
+	claimLease = 5 * time.Minute
+	maxBackoff = time.Hour
+)
+
+// Dispatcher polls for due pending reminders, claims them and hands them to a Notifier
+type Dispatcher struct {
+	store       store.Store
+	notifier    Notifier
+	interval    time.Duration
+	maxAge      time.Duration
+	batchSize   int
+	maxAttempts int
+	now         func() time.Time
+}
+
+// NewDispatcher creates a dispatcher that checks for due reminders every
+// interval and skips those due more than maxAge ago
+func NewDispatcher(st store.Store, notifier Notifier, interval, maxAge time.Duration) *Dispatcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	return &Dispatcher{
+		store:       st,
+		notifier:    notifier,
+		interval:    interval,
+		maxAge:      maxAge,
+		batchSize:   defaultBatchSize,
+		maxAttempts: defaultMaxAttempts,
+		now:         time.Now,
+	}
+}
+
+// Run dispatches due reminders on every tick until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	slog.Info("Notification dispatcher started", "notifier", d.notifier.Name(), "interval", d.interval)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Notification dispatch failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Notification dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue notifies one batch of due reminders and returns how many were delivered
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	now := d.now().UTC()
+	reminders := d.store.Reminders()
+
+	due, err := reminders.ListDueForNotification(ctx, now.Add(-d.maxAge), now, d.maxAttempts, d.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, r := range due {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+
+		attempts, claimed, err := reminders.ClaimForNotification(ctx, r.Reminder.ID, now, now.Add(claimLease))
+		if err != nil {
+			return delivered, err
+		}
+		if !claimed {
+			// Another dispatcher got there first
+			continue
+		}
+
+		sendErr := d.notifier.Notify(ctx, newNotification(r))
+		if sendErr != nil && ctx.Err() != nil {
+			// Shutting down: the lease expires and the reminder is retried on next start
+			return delivered, ctx.Err()
+		}
+
+		at := d.now().UTC()
+		if err := reminders.RecordNotificationAttempt(ctx, r.Reminder.ID, d.notifier.Name(), at, sendErr, at.Add(backoff(attempts))); err != nil {
+			return delivered, err
+		}
+		if sendErr != nil {
+			slog.Warn("Notification failed", "reminder_id", r.Reminder.ID, "notifier", d.notifier.Name(), "error", sendErr)
+			continue
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// newNotification flattens a due reminder into the notifier payload
+func newNotification(r store.DueReminder) Notification {
+	n := Notification{
+		ReminderID:   r.Reminder.ID,
+		ScheduleID:   r.Reminder.ScheduleID,
+		MedicineID:   r.Medicine.ID,
+		MedicineName: r.Medicine.Name,
+		UserID:       r.User.ID,
+		UserName:     r.User.Name,
+		UserEmail:    r.User.Email,
+		DueAt:        r.Reminder.ReminderDatetime,
+	}
+	// A dose step's amount replaces the medicine's usual dosage
+	if r.Reminder.Dose != nil {
+		n.Dosage = *r.Reminder.Dose
+	} else if r.Medicine.Dosage != nil {
+		n.Dosage = *r.Medicine.Dosage
+	}
+	if r.Medicine.Instructions != nil {
+		n.Instructions = *r.Medicine.Instructions
+	}
+	return n
+}
+
+// backoff doubles the retry delay per attempt starting at one minute, capped at maxBackoff
+func backoff(attempts int) time.Duration {
+	delay := time.Minute
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
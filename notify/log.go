@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogNotifier writes due reminders to the structured log; useful in development
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (LogNotifier) Name() string {
+	return "log"
+}
+
+func (LogNotifier) Notify(_ context.Context, n Notification) error {
+	slog.Info("Reminder due",
+		"reminder_id", n.ReminderID,
+		"user_id", n.UserID,
+		"medicine_id", n.MedicineID,
+		"due_at", n.DueAt,
+	)
+	return nil
+}
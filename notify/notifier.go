@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Notification is everything a notifier needs to tell a user a dose is due
+type Notification struct {
+	ReminderID   string    `json:"reminder_id"`
+	ScheduleID   string    `json:"schedule_id"`
+	MedicineID   string    `json:"medicine_id"`
+	MedicineName string    `json:"medicine_name"`
+	Dosage       string    `json:"dosage,omitempty"`
+	Instructions string    `json:"instructions,omitempty"`
+	UserID       string    `json:"user_id"`
+	UserName     string    `json:"user_name"`
+	UserEmail    string    `json:"user_email"`
+	DueAt        time.Time `json:"due_at"`
+}
+
+// Notifier delivers a due reminder to the user through some channel
+type Notifier interface {
+	// Name identifies the notifier in delivery attempt records
+	Name() string
+	// Notify delivers n, returning an error if it should be retried
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFromEnv builds the notifier selected by NOTIFIER ("log" or "webhook")
+func NotifierFromEnv() (Notifier, error) {
+	switch kind := os.Getenv("NOTIFIER"); kind {
+	case "", "log":
+		return NewLogNotifier(), nil
+	case "webhook":
+		url := os.Getenv("NOTIFY_WEBHOOK_URL")
+		if url == "" {
+			return nil, fmt.Errorf("NOTIFY_WEBHOOK_URL is required when NOTIFIER=webhook")
+		}
+		return NewWebhookNotifier(url, os.Getenv("NOTIFY_WEBHOOK_SECRET")), nil
+	default:
+		return nil, fmt.Errorf("unknown NOTIFIER %q", kind)
+	}
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"net/http"
+	"pillTickr-backend/authz"
 	"pillTickr-backend/handlers"
 	"pillTickr-backend/middleware"
 
@@ -14,96 +15,296 @@ type Route struct {
 	Pattern     string
 	HandlerFunc gin.HandlerFunc
 	Secured     bool
+	// Resource, when set on a secured route, is the kind of record named by
+	// the :id path parameter; requests for records the user does not own get 404
+	Resource authz.Resource
 }
 
 type Routes []Route
 
-func NewRoutes() Routes {
+func NewRoutes(h *handlers.Handler) Routes {
 	return Routes{
 		// --- Auth ---
 		{
 			Name:        "Register",
 			Method:      "POST",
 			Pattern:     "/auth/register",
-			HandlerFunc: handlers.Register,
+			HandlerFunc: h.Register,
 			Secured:     false,
 		},
 		{
 			Name:        "Login",
 			Method:      "POST",
 			Pattern:     "/auth/login",
-			HandlerFunc: handlers.Login,
+			HandlerFunc: h.Login,
 			Secured:     false,
 		},
 		{
 			Name:        "RefreshToken",
 			Method:      "POST",
 			Pattern:     "/auth/refresh",
-			HandlerFunc: handlers.RefreshToken,
+			HandlerFunc: h.RefreshToken,
 			Secured:     false,
 		},
+		{
+			Name:        "Logout",
+			Method:      "POST",
+			Pattern:     "/auth/logout",
+			HandlerFunc: h.Logout,
+			Secured:     false,
+		},
+		{
+			Name:        "LogoutAll",
+			Method:      "POST",
+			Pattern:     "/auth/logout-all",
+			HandlerFunc: h.LogoutAll,
+			Secured:     true,
+		},
+		// --- Account (secured) ---
+		{
+			Name:        "GetProfile",
+			Method:      "GET",
+			Pattern:     "/users/me",
+			HandlerFunc: h.GetProfile,
+			Secured:     true,
+		},
+		{
+			Name:        "UpdateProfile",
+			Method:      "PATCH",
+			Pattern:     "/users/me",
+			HandlerFunc: h.UpdateProfile,
+			Secured:     true,
+		},
+		{
+			Name:        "GetTravelPlan",
+			Method:      "GET",
+			Pattern:     "/users/me/travel",
+			HandlerFunc: h.GetTravelPlan,
+			Secured:     true,
+		},
+		{
+			Name:        "SetTravelPlan",
+			Method:      "PUT",
+			Pattern:     "/users/me/travel",
+			HandlerFunc: h.SetTravelPlan,
+			Secured:     true,
+		},
+		{
+			Name:        "DeleteTravelPlan",
+			Method:      "DELETE",
+			Pattern:     "/users/me/travel",
+			HandlerFunc: h.DeleteTravelPlan,
+			Secured:     true,
+		},
+		{
+			Name:        "DeleteAccount",
+			Method:      "DELETE",
+			Pattern:     "/users/me",
+			HandlerFunc: h.DeleteAccount,
+			Secured:     true,
+		},
 		// --- Reminders (secured) ---
 		{
 			Name:        "GetReminders",
 			Method:      "GET",
 			Pattern:     "/reminders",
-			HandlerFunc: handlers.GetReminders,
+			HandlerFunc: h.GetReminders,
 			Secured:     true,
 		},
 		{
 			Name:        "CreateReminder",
 			Method:      "POST",
 			Pattern:     "/reminders",
-			HandlerFunc: handlers.CreateReminder,
+			HandlerFunc: h.CreateReminder,
 			Secured:     true,
 		},
 		{
 			Name:        "PatchReminder",
 			Method:      "PATCH",
 			Pattern:     "/reminders/:id",
-			HandlerFunc: handlers.UpdateReminder,
+			HandlerFunc: h.UpdateReminder,
 			Secured:     true,
+			Resource:    authz.Reminder,
 		},
 		{
 			Name:        "DeleteReminder",
 			Method:      "DELETE",
 			Pattern:     "/reminders/:id",
-			HandlerFunc: handlers.DeleteReminder,
+			HandlerFunc: h.DeleteReminder,
 			Secured:     true,
+			Resource:    authz.Reminder,
 		},
 		{
 			Name:        "GetMedicines",
 			Method:      "GET",
 			Pattern:     "/medicines",
-			HandlerFunc: handlers.GetMedicines,
+			HandlerFunc: h.GetMedicines,
 			Secured:     true,
 		},
 		{
 			Name:        "CreateMedicine",
 			Method:      "POST",
 			Pattern:     "/medicines",
-			HandlerFunc: handlers.CreateMedicine,
+			HandlerFunc: h.CreateMedicine,
+			Secured:     true,
+		},
+		{
+			Name:        "GetMedicine",
+			Method:      "GET",
+			Pattern:     "/medicines/:id",
+			HandlerFunc: h.GetMedicine,
+			Secured:     true,
+			Resource:    authz.Medicine,
+		},
+		{
+			Name:        "ReplaceMedicine",
+			Method:      "PUT",
+			Pattern:     "/medicines/:id",
+			HandlerFunc: h.ReplaceMedicine,
+			Secured:     true,
+			Resource:    authz.Medicine,
+		},
+		{
+			Name:        "UpdateMedicine",
+			Method:      "PATCH",
+			Pattern:     "/medicines/:id",
+			HandlerFunc: h.UpdateMedicine,
+			Secured:     true,
+			Resource:    authz.Medicine,
+		},
+		{
+			Name:        "DeleteMedicine",
+			Method:      "DELETE",
+			Pattern:     "/medicines/:id",
+			HandlerFunc: h.DeleteMedicine,
 			Secured:     true,
+			Resource:    authz.Medicine,
 		},
 		{
 			Name:        "GetSchedules",
 			Method:      "GET",
 			Pattern:     "/medicines/:id/schedules",
-			HandlerFunc: handlers.GetSchedules,
+			HandlerFunc: h.GetSchedules,
 			Secured:     true,
+			Resource:    authz.Medicine,
 		},
 		{
 			Name:        "CreateSchedule",
 			Method:      "POST",
 			Pattern:     "/medicines/:id/schedules",
-			HandlerFunc: handlers.CreateSchedule,
+			HandlerFunc: h.CreateSchedule,
+			Secured:     true,
+			Resource:    authz.Medicine,
+		},
+		{
+			Name:        "GetSchedule",
+			Method:      "GET",
+			Pattern:     "/schedules/:id",
+			HandlerFunc: h.GetSchedule,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "UpdateSchedule",
+			Method:      "PATCH",
+			Pattern:     "/schedules/:id",
+			HandlerFunc: h.UpdateSchedule,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "GetScheduleHistory",
+			Method:      "GET",
+			Pattern:     "/schedules/:id/history",
+			HandlerFunc: h.GetScheduleHistory,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "DeleteSchedule",
+			Method:      "DELETE",
+			Pattern:     "/schedules/:id",
+			HandlerFunc: h.DeleteSchedule,
 			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "GetScheduleTimes",
+			Method:      "GET",
+			Pattern:     "/schedules/:id/times",
+			HandlerFunc: h.GetScheduleTimes,
+			Secured:     true,
+			Resource:    authz.Schedule,
 		},
 		{
 			Name:        "AddScheduleTime",
 			Method:      "POST",
 			Pattern:     "/schedules/:id/times",
-			HandlerFunc: handlers.AddScheduleTime,
+			HandlerFunc: h.AddScheduleTime,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "ReplaceScheduleTimes",
+			Method:      "PUT",
+			Pattern:     "/schedules/:id/times",
+			HandlerFunc: h.ReplaceScheduleTimes,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "DeleteScheduleTime",
+			Method:      "DELETE",
+			Pattern:     "/schedules/:id/times/:timeId",
+			HandlerFunc: h.DeleteScheduleTime,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "GetDoseSteps",
+			Method:      "GET",
+			Pattern:     "/schedules/:id/dose-steps",
+			HandlerFunc: h.GetDoseSteps,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "ReplaceDoseSteps",
+			Method:      "PUT",
+			Pattern:     "/schedules/:id/dose-steps",
+			HandlerFunc: h.ReplaceDoseSteps,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "GetSchedulePauses",
+			Method:      "GET",
+			Pattern:     "/schedules/:id/pauses",
+			HandlerFunc: h.GetSchedulePauses,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "PauseSchedule",
+			Method:      "POST",
+			Pattern:     "/schedules/:id/pause",
+			HandlerFunc: h.PauseSchedule,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "ResumeSchedule",
+			Method:      "POST",
+			Pattern:     "/schedules/:id/resume",
+			HandlerFunc: h.ResumeSchedule,
+			Secured:     true,
+			Resource:    authz.Schedule,
+		},
+		{
+			Name:        "GetAdherence",
+			Method:      "GET",
+			Pattern:     "/adherence",
+			HandlerFunc: h.GetAdherence,
 			Secured:     true,
 		},
 		// --- Health Check ---
@@ -119,12 +320,17 @@ func NewRoutes() Routes {
 	}
 }
 
-// Attach routes to the server
-func AttachRoutes(server *gin.RouterGroup, routes Routes) {
+// Attach routes to the server; owners resolves who owns the records named by secured routes
+func AttachRoutes(server *gin.RouterGroup, routes Routes, owners authz.OwnerResolver) {
 	for _, route := range routes {
 		if route.Secured {
-			// Wrap with RequireAuth middleware
-			server.Handle(route.Method, route.Pattern, middleware.RequireAuth(), route.HandlerFunc)
+			// Wrap with RequireAuth middleware, then check ownership of the addressed record
+			chain := []gin.HandlerFunc{middleware.RequireAuth()}
+			if route.Resource != "" {
+				chain = append(chain, middleware.RequireOwnership(owners, route.Resource, "id"))
+			}
+			chain = append(chain, route.HandlerFunc)
+			server.Handle(route.Method, route.Pattern, chain...)
 		} else {
 			server.Handle(route.Method, route.Pattern, route.HandlerFunc)
 		}
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+// StepOn returns the index of the dose step covering day's calendar date, or
+// -1 if none does. steps must be ordered by start date without overlapping;
+// a step without an end date runs until the next one starts.
+func StepOn(steps []models.DoseStep, day time.Time) int {
+	date := civilDate(day)
+	found := -1
+	for i, st := range steps {
+		if date.Before(civilDate(st.StartDate)) {
+			break
+		}
+		if st.EndDate == nil || !date.After(civilDate(*st.EndDate)) {
+			found = i
+		} else {
+			found = -1
+		}
+	}
+	return found
+}
+
+// NextStep returns the index of the first dose step starting after day's
+// calendar date, or -1 if there is none
+func NextStep(steps []models.DoseStep, day time.Time) int {
+	date := civilDate(day)
+	for i, st := range steps {
+		if civilDate(st.StartDate).After(date) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,238 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"pillTickr-backend/authz"
+	"pillTickr-backend/models"
+	"pillTickr-backend/store"
+)
+
+const (
+	DefaultWindow   = 7 * 24 * time.Hour
+	DefaultInterval = time.Hour
+)
+
+// Generator materializes reminders from schedules and their intake times over
+// a rolling window, so clients and the dispatcher only ever deal with rows
+type Generator struct {
+	store    store.Store
+	window   time.Duration
+	interval time.Duration
+	location *time.Location // for users without a time zone of their own
+	now      func() time.Time
+}
+
+// NewGenerator creates a generator that keeps reminders materialized window
+// ahead of now and tops them up every interval
+func NewGenerator(st store.Store, window, interval time.Duration) *Generator {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Generator{
+		store:    st,
+		window:   window,
+		interval: interval,
+		location: time.Local,
+		now:      time.Now,
+	}
+}
+
+// Run generates reminders immediately and then on every tick until ctx is done
+func (g *Generator) Run(ctx context.Context) {
+	slog.Info("Reminder generator started", "window", g.window, "interval", g.interval)
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := g.GenerateAll(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Reminder generation failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Reminder generator stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GenerateAll tops up reminders for every schedule that is still running and
+// returns how many reminders were created
+func (g *Generator) GenerateAll(ctx context.Context) (int, error) {
+	now := g.now().UTC()
+
+	// Schedules end on their owner's date, which can still be yesterday in UTC terms
+	ids, err := g.store.Schedules().ListSchedulesToGenerate(ctx, now.Add(-24*time.Hour), now.Add(g.window))
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+		created, err := g.generate(ctx, id, now, false)
+		if err != nil {
+			// One broken schedule must not stop the others
+			slog.Error("Failed to generate reminders for schedule", "schedule_id", id, "error", err)
+			continue
+		}
+		total += created
+	}
+
+	if total > 0 {
+		slog.Info("Reminders generated", "count", total, "schedules", len(ids))
+	}
+	return total, nil
+}
+
+// Regenerate discards the future pending reminders generated for a schedule
+// and builds them again from its current definition. Reminders that were
+// already acted on, past reminders and manually created ones are kept.
+func (g *Generator) Regenerate(ctx context.Context, scheduleID string) (int, error) {
+	return g.generate(ctx, scheduleID, g.now().UTC(), true)
+}
+
+// generate materializes reminders for one schedule from where it left off (or
+// from now when reset) up to the end of the window, in a single transaction
+func (g *Generator) generate(ctx context.Context, scheduleID string, now time.Time, reset bool) (int, error) {
+	created := 0
+	err := g.store.WithTx(ctx, func(tx store.Store) error {
+		if reset {
+			if err := tx.Reminders().DeleteFutureGeneratedReminders(ctx, scheduleID, now); err != nil {
+				return err
+			}
+		}
+
+		s, err := tx.Schedules().GetSchedule(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		// Archived medicines keep their history but get nothing new
+		archived, err := tx.Medicines().IsMedicineArchived(ctx, s.MedicineID)
+		if err != nil || archived {
+			return err
+		}
+		zones, err := g.ownerZones(ctx, tx, scheduleID)
+		if err != nil {
+			return err
+		}
+
+		// Never backfill the past: reminders start at now, or where the last run stopped
+		from := now
+		if !reset && s.GeneratedUntil != nil && s.GeneratedUntil.After(from) {
+			from = *s.GeneratedUntil
+		}
+		to := now.Add(g.window)
+
+		var occurrences []Occurrence
+		if s.Frequency == "interval" {
+			lastTaken, err := tx.Reminders().LastTakenAt(ctx, scheduleID)
+			if err != nil {
+				return err
+			}
+			occurrences = IntervalOccurrences(s, lastTaken, from, to, zones.Home)
+		} else {
+			times, err := tx.Schedules().ListScheduleTimes(ctx, scheduleID)
+			if err != nil {
+				return err
+			}
+			if occurrences, err = Occurrences(s, times, from, to, zones); err != nil {
+				return err
+			}
+		}
+
+		// A fixed course stops once its doses are taken or pending; missed
+		// ones leave room for a make-up dose at the end
+		planned := 0
+		if s.TotalDoses > 0 {
+			if planned, err = tx.Reminders().CountPlannedDoses(ctx, scheduleID); err != nil {
+				return err
+			}
+		}
+
+		steps, err := tx.Schedules().ListDoseSteps(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		pauses, err := tx.Schedules().ListPauses(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		// Reminders record the version of the schedule they come from
+		var versionID *string
+		version, err := tx.Schedules().CurrentScheduleVersion(ctx, scheduleID)
+		switch {
+		case err == nil:
+			versionID = &version.ID
+		case !errors.Is(err, store.ErrNotFound):
+			return err
+		}
+
+		for _, o := range occurrences {
+			if s.TotalDoses > 0 && planned >= s.TotalDoses {
+				break
+			}
+			if PauseAt(pauses, o.At) >= 0 {
+				continue
+			}
+			r := models.Reminder{ScheduleID: scheduleID, ReminderDatetime: o.At, VersionID: versionID}
+			if o.TimeID != "" {
+				timeID := o.TimeID
+				r.TimeID = &timeID
+			}
+			if i := StepOn(steps, zones.In(o.At)); i >= 0 {
+				r.Dose = &steps[i].Dose
+			}
+			inserted, err := tx.Reminders().InsertGeneratedReminder(ctx, r)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				created++
+				planned++
+			}
+		}
+
+		if to.After(from) {
+			return tx.Schedules().SetGeneratedUntil(ctx, scheduleID, to)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// ownerZones returns the time zones of the user owning a schedule: their own,
+// or the generator's for users who have not set one, and that of any trip
+func (g *Generator) ownerZones(ctx context.Context, tx store.Store, scheduleID string) (Zones, error) {
+	owner, err := tx.OwnerOf(ctx, authz.Schedule, scheduleID)
+	if err != nil {
+		return Zones{}, err
+	}
+	user, err := tx.Users().GetUser(ctx, owner)
+	if err != nil {
+		return Zones{}, err
+	}
+	var trip *models.TravelPlan
+	plan, err := tx.Users().GetTravelPlan(ctx, owner)
+	switch {
+	case err == nil:
+		trip = &plan
+	case !errors.Is(err, store.ErrNotFound):
+		return Zones{}, err
+	}
+	return NewZones(user.Location(g.location), trip), nil
+}
@@ -0,0 +1,222 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+// Occurrence is one dose moment produced by expanding a schedule
+type Occurrence struct {
+	At     time.Time
+	TimeID string // intake time it comes from; empty for interval schedules
+}
+
+// ParseIntakeTime parses an "HH:MM" intake time into hour and minute
+func ParseIntakeTime(value string) (int, int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid intake time %q, expected HH:MM", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// dateOnly returns midnight of t's calendar date in loc
+func dateOnly(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// wallClock returns the instant the clock on the wall shows hour:minute on
+// the given date in loc. A time skipped when clocks go forward is read with
+// the offset from before the change, so it lands just after the gap (02:30
+// becomes 03:30); a time repeated when they go back is its first occurrence.
+func wallClock(year int, month time.Month, day, hour, minute int, loc *time.Location) time.Time {
+	naive := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	shows := func(t time.Time) bool {
+		l := t.In(loc)
+		return l.Day() == day && l.Hour() == hour && l.Minute() == minute
+	}
+
+	// Any transition on the day lies between the offsets a day either side
+	_, before := naive.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := naive.Add(24 * time.Hour).In(loc).Zone()
+	early := naive.Add(-time.Duration(before) * time.Second)
+	late := naive.Add(-time.Duration(after) * time.Second)
+	if late.Before(early) {
+		early, late = late, early
+	}
+
+	switch {
+	case shows(early):
+		return early
+	case shows(late):
+		return late
+	default:
+		// In the gap: keep the offset from before it
+		return naive.Add(-time.Duration(before) * time.Second)
+	}
+}
+
+// activeOn reports whether a schedule has doses on the given calendar day.
+// ruleDays holds the days a custom schedule's RRULE yields, keyed by dateLayout.
+func activeOn(s models.Schedule, day time.Time, loc *time.Location, ruleDays map[string]bool) bool {
+	start := dateOnly(s.StartDate, loc)
+	if day.Before(start) {
+		return false
+	}
+	if s.EndDate != nil && day.After(dateOnly(*s.EndDate, loc)) {
+		return false
+	}
+
+	switch s.Frequency {
+	case "daily":
+		return true
+	case "weekly":
+		// Without explicit weekdays a weekly schedule repeats on its start weekday
+		if s.Weekdays == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return s.Weekdays.Has(day.Weekday())
+	case "custom":
+		return ruleDays[day.Format(dateLayout)]
+	case "cyclic":
+		n := CycleDay(s, day)
+		return n > 0 && n <= s.CycleActiveDays
+	default:
+		return false
+	}
+}
+
+// CycleDay returns the 1-based day of a cyclic schedule's cycle that falls on
+// day's calendar date, or 0 if the schedule is not cyclic or day precedes it.
+// Days up to CycleActiveDays are active, the rest are rest days.
+func CycleDay(s models.Schedule, day time.Time) int {
+	length := s.CycleActiveDays + s.CycleRestDays
+	if s.Frequency != "cyclic" || length <= 0 {
+		return 0
+	}
+	elapsed := daysBetween(civilDate(s.StartDate), civilDate(day))
+	if elapsed < 0 {
+		return 0
+	}
+	return elapsed%length + 1
+}
+
+// dateLayout is how days are written in ExDates
+const dateLayout = "2006-01-02"
+
+// customDays expands a custom schedule's RRULE over the days touching
+// [from, to), leaving out its EXDATEs
+func customDays(s models.Schedule, from, to time.Time, loc *time.Location) (map[string]bool, error) {
+	rule, err := ParseRRule(s.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", s.ID, err)
+	}
+	excluded := make(map[string]bool, len(s.ExDates))
+	for _, d := range s.ExDates {
+		excluded[d] = true
+	}
+
+	days := map[string]bool{}
+	lastDay := dateOnly(to.In(loc), loc).AddDate(0, 0, 1)
+	for _, day := range rule.Days(dateOnly(s.StartDate, loc), from.In(loc), lastDay) {
+		if key := day.Format(dateLayout); !excluded[key] {
+			days[key] = true
+		}
+	}
+	return days, nil
+}
+
+// Occurrences expands a schedule and its intake times into the dose moments
+// in [from, to). Dates and intake times are wall-clock values in the time
+// zone its owner follows on each day, and moments are returned in UTC.
+func Occurrences(s models.Schedule, times []models.ScheduleTime, from, to time.Time, zones Zones) ([]Occurrence, error) {
+	type clock struct {
+		hour, minute int
+		id           string
+	}
+	clocks := make([]clock, 0, len(times))
+	for _, t := range times {
+		h, m, err := ParseIntakeTime(t.IntakeTime)
+		if err != nil {
+			return nil, err
+		}
+		clocks = append(clocks, clock{h, m, t.ID})
+	}
+
+	var out []Occurrence
+	if len(clocks) == 0 || !from.Before(to) {
+		return out, nil
+	}
+
+	// Days read in a zone other than home can start up to a day either side
+	// of home's, so a day more is walked on each end and the rest clipped
+	home := zones.Home
+	first := dateOnly(from.In(home), home).AddDate(0, 0, -1)
+	last := to.AddDate(0, 0, 1)
+
+	var ruleDays map[string]bool
+	if s.Frequency == "custom" {
+		var err error
+		if ruleDays, err = customDays(s, first, last, home); err != nil {
+			return nil, err
+		}
+	}
+
+	for d := first; d.Before(last); d = d.AddDate(0, 0, 1) {
+		loc := zones.On(d)
+		day := dateOnly(d, loc)
+		if !activeOn(s, day, loc, ruleDays) {
+			continue
+		}
+		for _, c := range clocks {
+			at := wallClock(day.Year(), day.Month(), day.Day(), c.hour, c.minute, loc)
+			if at.Before(from) || !at.Before(to) {
+				continue
+			}
+			out = append(out, Occurrence{At: at.UTC(), TimeID: c.id})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out, nil
+}
+
+// IntervalOccurrences lists the doses of an interval schedule in [from, to).
+// Doses follow the last dose taken, or the first dose while none was taken,
+// every IntervalHours of elapsed time, so they run across midnight and DST
+// changes unaffected. Dates bounding the schedule are read in loc.
+func IntervalOccurrences(s models.Schedule, lastTaken *time.Time, from, to time.Time, loc *time.Location) []Occurrence {
+	var out []Occurrence
+	if s.IntervalHours <= 0 || s.FirstDoseAt == nil {
+		return out
+	}
+	interval := time.Duration(s.IntervalHours) * time.Hour
+
+	next := *s.FirstDoseAt
+	if lastTaken != nil && !lastTaken.Before(next) {
+		next = lastTaken.Add(interval)
+	}
+
+	if start := dateOnly(s.StartDate, loc); from.Before(start) {
+		from = start
+	}
+	if s.EndDate != nil {
+		if end := dateOnly(*s.EndDate, loc).AddDate(0, 0, 1); end.Before(to) {
+			to = end
+		}
+	}
+
+	// Jump over whole intervals before from instead of walking them
+	if next.Before(from) {
+		steps := (from.Sub(next) + interval - 1) / interval
+		next = next.Add(steps * interval)
+	}
+	for ; next.Before(to); next = next.Add(interval) {
+		out = append(out, Occurrence{At: next.UTC()})
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"pillTickr-backend/models"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestWallClock(t *testing.T) {
+	tests := []struct {
+		name         string
+		zone         string
+		date         string
+		hour, minute int
+		want         string // UTC
+	}{
+		{"Berlin before spring forward", "Europe/Berlin", "2025-03-30", 1, 30, "2025-03-30T00:30:00Z"},
+		{"Berlin spring forward gap", "Europe/Berlin", "2025-03-30", 2, 30, "2025-03-30T01:30:00Z"},
+		{"Berlin after spring forward", "Europe/Berlin", "2025-03-30", 3, 30, "2025-03-30T01:30:00Z"},
+		{"Berlin fall back repeated hour", "Europe/Berlin", "2025-10-26", 2, 30, "2025-10-26T00:30:00Z"},
+		{"Berlin after fall back", "Europe/Berlin", "2025-10-26", 3, 30, "2025-10-26T02:30:00Z"},
+		{"New York spring forward gap", "America/New_York", "2025-03-09", 2, 30, "2025-03-09T07:30:00Z"},
+		{"New York after spring forward", "America/New_York", "2025-03-09", 8, 0, "2025-03-09T12:00:00Z"},
+		{"New York fall back repeated hour", "America/New_York", "2025-11-02", 1, 30, "2025-11-02T05:30:00Z"},
+		{"New York after fall back", "America/New_York", "2025-11-02", 2, 30, "2025-11-02T07:30:00Z"},
+		{"New York ordinary day", "America/New_York", "2025-07-01", 8, 0, "2025-07-01T12:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoad(t, tt.zone)
+			day, _ := time.Parse(dateLayout, tt.date)
+			got := wallClock(day.Year(), day.Month(), day.Day(), tt.hour, tt.minute, loc)
+			if want, _ := time.Parse(time.RFC3339, tt.want); !got.Equal(want) {
+				t.Errorf("wallClock(%s %02d:%02d) = %s, want %s", tt.date, tt.hour, tt.minute, got.UTC().Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrencesAcrossDST(t *testing.T) {
+	loc := mustLoad(t, "Europe/Berlin")
+	s := models.Schedule{ID: "1", Frequency: "daily", StartDate: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}
+	times := []models.ScheduleTime{{ID: "1", IntakeTime: "02:30"}}
+
+	from := time.Date(2025, 3, 29, 0, 0, 0, 0, loc)
+	to := time.Date(2025, 4, 1, 0, 0, 0, 0, loc)
+	got, err := Occurrences(s, times, from, to, NewZones(loc, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One dose a day, including the day 02:30 does not exist
+	want := []string{"2025-03-29T01:30:00Z", "2025-03-30T01:30:00Z", "2025-03-31T00:30:00Z"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d occurrences, want %d: %v", len(got), len(want), got)
+	}
+	for i, o := range got {
+		if o.At.Format(time.RFC3339) != want[i] {
+			t.Errorf("occurrence %d = %s, want %s", i, o.At.Format(time.RFC3339), want[i])
+		}
+	}
+}
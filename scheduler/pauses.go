@@ -0,0 +1,18 @@
+package scheduler
+
+import (
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+// PauseAt returns the index of the pause covering t, or -1 if the schedule is
+// not on hold then
+func PauseAt(pauses []models.SchedulePause, t time.Time) int {
+	for i, p := range pauses {
+		if !t.Before(p.PausedAt) && (p.ResumedAt == nil || t.Before(*p.ResumedAt)) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,370 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RRule is an RFC 5545 recurrence rule picking the days a custom schedule
+// runs on. Times of day come from the schedule's intake times, so only the
+// day-level parts are supported: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY),
+// INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS and WKST.
+type RRule struct {
+	freq       string
+	interval   int
+	count      int        // 0 means unlimited
+	until      *time.Time // last day, inclusive
+	byDay      []ruleWeekday
+	byMonthDay []int
+	byMonth    []time.Month
+	bySetPos   []int
+	weekStart  time.Weekday
+}
+
+// ruleWeekday is a BYDAY entry such as "TU" (n = 0) or "2TU" / "-1FR"
+type ruleWeekday struct {
+	n       int
+	weekday time.Weekday
+}
+
+var ruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRRule parses the value of an RRULE property, such as
+// "FREQ=MONTHLY;BYDAY=2TU". A leading "RRULE:" is accepted.
+func ParseRRule(value string) (*RRule, error) {
+	value = strings.TrimSpace(value)
+	if len(value) >= 6 && strings.EqualFold(value[:6], "RRULE:") {
+		value = value[6:]
+	}
+	if value == "" {
+		return nil, fmt.Errorf("empty RRULE")
+	}
+
+	r := &RRule{interval: 1, weekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if !ok || name == "" || val == "" {
+			return nil, fmt.Errorf("invalid RRULE part %q, expected NAME=VALUE", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("RRULE part %s is given twice", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch val {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = val
+			case "HOURLY", "MINUTELY", "SECONDLY":
+				return nil, fmt.Errorf("FREQ=%s is not supported; intake times set the time of day", val)
+			default:
+				return nil, fmt.Errorf("invalid FREQ %q", val)
+			}
+		case "INTERVAL":
+			r.interval, err = parseRuleInt(name, val, 1, 0)
+		case "COUNT":
+			r.count, err = parseRuleInt(name, val, 1, 0)
+		case "UNTIL":
+			r.until, err = parseRuleUntil(val)
+		case "BYDAY":
+			r.byDay, err = parseRuleWeekdays(val)
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseRuleInts(name, val, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseRuleInts(name, val, 12)
+			for _, m := range months {
+				if m < 0 {
+					return nil, fmt.Errorf("invalid BYMONTH %d", m)
+				}
+				r.byMonth = append(r.byMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			r.bySetPos, err = parseRuleInts(name, val, 366)
+		case "WKST":
+			wd, ok := ruleWeekdays[val]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", val)
+			}
+			r.weekStart = wd
+		case "BYHOUR", "BYMINUTE", "BYSECOND":
+			return nil, fmt.Errorf("RRULE part %s is not supported; intake times set the time of day", name)
+		default:
+			return nil, fmt.Errorf("RRULE part %s is not supported", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.freq == "" {
+		return nil, fmt.Errorf("RRULE needs a FREQ")
+	}
+	if r.count > 0 && r.until != nil {
+		return nil, fmt.Errorf("RRULE cannot have both COUNT and UNTIL")
+	}
+	for _, d := range r.byDay {
+		if d.n != 0 && r.freq != "MONTHLY" && r.freq != "YEARLY" {
+			return nil, fmt.Errorf("BYDAY with a position such as 2TU needs FREQ=MONTHLY or YEARLY")
+		}
+	}
+	if len(r.bySetPos) > 0 {
+		if r.freq == "DAILY" {
+			return nil, fmt.Errorf("BYSETPOS is not supported with FREQ=DAILY")
+		}
+		if len(r.byDay) == 0 && len(r.byMonthDay) == 0 && len(r.byMonth) == 0 {
+			return nil, fmt.Errorf("BYSETPOS needs BYDAY, BYMONTHDAY or BYMONTH")
+		}
+	}
+	return r, nil
+}
+
+// parseRuleInt parses an integer of at least min and, when max > 0, at most max
+func parseRuleInt(name, val string, min, max int) (int, error) {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < min || (max > 0 && n > max) {
+		return 0, fmt.Errorf("invalid %s %q", name, val)
+	}
+	return n, nil
+}
+
+// parseRuleInts parses a list of non-zero integers between -max and max
+func parseRuleInts(name, val string, max int) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(val, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n == 0 || n < -max || n > max {
+			return nil, fmt.Errorf("invalid %s value %q", name, item)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// parseRuleUntil reads the date part of an UNTIL value, which may be a DATE
+// (20251231) or a DATE-TIME (20251231T235959Z)
+func parseRuleUntil(val string) (*time.Time, error) {
+	date, _, _ := strings.Cut(val, "T")
+	t, err := time.Parse("20060102", date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UNTIL %q, expected YYYYMMDD or YYYYMMDDTHHMMSSZ", val)
+	}
+	return &t, nil
+}
+
+func parseRuleWeekdays(val string) ([]ruleWeekday, error) {
+	var out []ruleWeekday
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY value %q", item)
+		}
+		wd, ok := ruleWeekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY value %q", item)
+		}
+		d := ruleWeekday{weekday: wd}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid BYDAY value %q", item)
+			}
+			d.n = n
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// Days returns the days in [from, to) the rule puts on a schedule starting on
+// start, as UTC midnights. All arguments are read as calendar dates. Days
+// before start are never included; COUNT is counted from start.
+func (r *RRule) Days(start, from, to time.Time) []time.Time {
+	start, from, to = civilDate(start), civilDate(from), civilDate(to)
+	rule := r.anchored(start)
+
+	last := to.AddDate(0, 0, -1)
+	if rule.until != nil && rule.until.Before(last) {
+		last = *rule.until
+	}
+
+	var days []time.Time
+	matched := 0
+	for day := start; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if !rule.matches(start, day) {
+			continue
+		}
+		matched++
+		if !day.Before(from) {
+			days = append(days, day)
+		}
+		if rule.count > 0 && matched >= rule.count {
+			break
+		}
+	}
+	return days
+}
+
+// anchored fills in what RFC 5545 takes from DTSTART when the rule leaves it
+// out: a weekly rule repeats on the start weekday, a monthly one on the start
+// day of the month and a yearly one on the start date.
+func (r *RRule) anchored(start time.Time) *RRule {
+	rule := *r
+	switch rule.freq {
+	case "WEEKLY":
+		if len(rule.byDay) == 0 {
+			rule.byDay = []ruleWeekday{{weekday: start.Weekday()}}
+		}
+	case "MONTHLY":
+		if len(rule.byDay) == 0 && len(rule.byMonthDay) == 0 {
+			rule.byMonthDay = []int{start.Day()}
+		}
+	case "YEARLY":
+		if len(rule.byDay) == 0 && len(rule.byMonthDay) == 0 {
+			if len(rule.byMonth) == 0 {
+				rule.byMonth = []time.Month{start.Month()}
+			}
+			rule.byMonthDay = []int{start.Day()}
+		}
+	}
+	return &rule
+}
+
+// matches reports whether day is an occurrence, ignoring COUNT and UNTIL
+func (r *RRule) matches(start, day time.Time) bool {
+	if !r.inInterval(start, day) || !r.matchesBy(day) {
+		return false
+	}
+	if len(r.bySetPos) == 0 {
+		return true
+	}
+
+	var candidates []time.Time
+	periodStart, periodEnd := r.period(day)
+	for d := periodStart; d.Before(periodEnd); d = d.AddDate(0, 0, 1) {
+		if r.matchesBy(d) {
+			candidates = append(candidates, d)
+		}
+	}
+	for _, pos := range r.bySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(candidates) + pos
+		}
+		if i >= 0 && i < len(candidates) && candidates[i].Equal(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// inInterval reports whether day falls in a period the rule's INTERVAL selects
+func (r *RRule) inInterval(start, day time.Time) bool {
+	var periods int
+	switch r.freq {
+	case "DAILY":
+		periods = daysBetween(start, day)
+	case "WEEKLY":
+		periods = daysBetween(r.weekOf(start), r.weekOf(day)) / 7
+	case "MONTHLY":
+		periods = (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+	case "YEARLY":
+		periods = day.Year() - start.Year()
+	}
+	return periods%r.interval == 0
+}
+
+// matchesBy checks day against the BYMONTH, BYMONTHDAY and BYDAY filters
+func (r *RRule) matchesBy(day time.Time) bool {
+	if len(r.byMonth) > 0 && !slices.Contains(r.byMonth, day.Month()) {
+		return false
+	}
+
+	if len(r.byMonthDay) > 0 {
+		monthLen := daysIn(day.Year(), day.Month())
+		ok := false
+		for _, md := range r.byMonthDay {
+			if md == day.Day() || (md < 0 && monthLen+md+1 == day.Day()) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	if len(r.byDay) > 0 {
+		ok := false
+		for _, d := range r.byDay {
+			if d.weekday == day.Weekday() && (d.n == 0 || r.nthWeekday(day, d.n)) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// nthWeekday reports whether day is the nth of its weekday (counting from the
+// end when n < 0) in its month, or in its year for yearly rules without BYMONTH
+func (r *RRule) nthWeekday(day time.Time, n int) bool {
+	index, length := day.Day(), daysIn(day.Year(), day.Month())
+	if r.freq == "YEARLY" && len(r.byMonth) == 0 {
+		index, length = day.YearDay(), time.Date(day.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	}
+	if n > 0 {
+		return (index-1)/7+1 == n
+	}
+	return (length-index)/7+1 == -n
+}
+
+// period returns the week, month or year holding day, as [start, end)
+func (r *RRule) period(day time.Time) (time.Time, time.Time) {
+	switch r.freq {
+	case "WEEKLY":
+		start := r.weekOf(day)
+		return start, start.AddDate(0, 0, 7)
+	case "MONTHLY":
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		start := time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0)
+	}
+}
+
+// weekOf returns the first day of the week holding day, weeks starting on WKST
+func (r *RRule) weekOf(day time.Time) time.Time {
+	offset := (int(day.Weekday()) - int(r.weekStart) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// civilDate returns t's calendar date as a UTC midnight, so day arithmetic is
+// never affected by DST
+func civilDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
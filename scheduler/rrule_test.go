@@ -0,0 +1,188 @@
+package scheduler
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func formatDays(days []time.Time) []string {
+	out := make([]string, len(days))
+	for i, d := range days {
+		out[i] = d.Format(dateLayout)
+	}
+	return out
+}
+
+func TestRRuleDays(t *testing.T) {
+	tests := []struct {
+		name            string
+		rule            string
+		start, from, to string
+		want            []string
+	}{
+		{
+			name:  "second Tuesday of each month",
+			rule:  "FREQ=MONTHLY;BYDAY=2TU",
+			start: "2025-01-01", from: "2025-01-01", to: "2025-04-01",
+			want: []string{"2025-01-14", "2025-02-11", "2025-03-11"},
+		},
+		{
+			name:  "every second Tuesday",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+			start: "2025-01-07", from: "2025-01-01", to: "2025-02-20",
+			want: []string{"2025-01-07", "2025-01-21", "2025-02-04", "2025-02-18"},
+		},
+		{
+			name:  "first day of each month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=1",
+			start: "2025-01-15", from: "2025-01-01", to: "2025-04-02",
+			want: []string{"2025-02-01", "2025-03-01", "2025-04-01"},
+		},
+		{
+			name:  "last Friday of each month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			start: "2025-01-01", from: "2025-01-01", to: "2025-04-01",
+			want: []string{"2025-01-31", "2025-02-28", "2025-03-28"},
+		},
+		{
+			name:  "last weekday of each month",
+			rule:  "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			start: "2025-05-01", from: "2025-05-01", to: "2025-07-01",
+			want: []string{"2025-05-30", "2025-06-30"},
+		},
+		{
+			name:  "monthly on the start day by default",
+			rule:  "FREQ=MONTHLY",
+			start: "2025-01-20", from: "2025-01-01", to: "2025-04-01",
+			want: []string{"2025-01-20", "2025-02-20", "2025-03-20"},
+		},
+		{
+			name:  "COUNT stops after that many days",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: "2025-01-01", from: "2025-01-01", to: "2025-02-01",
+			want: []string{"2025-01-01", "2025-01-02", "2025-01-03"},
+		},
+		{
+			name:  "COUNT is counted from the start, not the window",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: "2025-01-01", from: "2025-01-02", to: "2025-02-01",
+			want: []string{"2025-01-02", "2025-01-03"},
+		},
+		{
+			name:  "COUNT with a weekly rule",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=3",
+			start: "2025-01-06", from: "2025-01-01", to: "2025-02-01",
+			want: []string{"2025-01-06", "2025-01-09", "2025-01-13"},
+		},
+		{
+			name:  "UNTIL is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20250103",
+			start: "2025-01-01", from: "2025-01-01", to: "2025-02-01",
+			want: []string{"2025-01-01", "2025-01-02", "2025-01-03"},
+		},
+		{
+			name:  "UNTIL as a date-time",
+			rule:  "FREQ=WEEKLY;UNTIL=20250120T235959Z",
+			start: "2025-01-06", from: "2025-01-01", to: "2025-02-01",
+			want: []string{"2025-01-06", "2025-01-13", "2025-01-20"},
+		},
+		{
+			name:  "nothing before the start",
+			rule:  "FREQ=DAILY",
+			start: "2025-01-30", from: "2025-01-28", to: "2025-02-01",
+			want: []string{"2025-01-30", "2025-01-31"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+			}
+			got := formatDays(rule.Days(day(tt.start), day(tt.from), day(tt.to)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Days = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRRuleRejects(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string // part of the error
+	}{
+		{"", "empty RRULE"},
+		{"BYDAY=MO", "needs a FREQ"},
+		{"FREQ=HOURLY", "not supported"},
+		{"FREQ=FORTNIGHTLY", "invalid FREQ"},
+		{"FREQ", "expected NAME=VALUE"},
+		{"FREQ=DAILY;FREQ=WEEKLY", "given twice"},
+		{"FREQ=DAILY;INTERVAL=0", "invalid INTERVAL"},
+		{"FREQ=DAILY;COUNT=3;UNTIL=20250101", "both COUNT and UNTIL"},
+		{"FREQ=DAILY;UNTIL=2025-01-01", "invalid UNTIL"},
+		{"FREQ=WEEKLY;BYDAY=2TU", "needs FREQ=MONTHLY or YEARLY"},
+		{"FREQ=MONTHLY;BYDAY=XX", "invalid BYDAY"},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", "invalid BYMONTHDAY"},
+		{"FREQ=YEARLY;BYMONTH=13", "invalid BYMONTH"},
+		{"FREQ=DAILY;BYSETPOS=1", "BYSETPOS is not supported"},
+		{"FREQ=MONTHLY;BYSETPOS=1", "BYSETPOS needs"},
+		{"FREQ=DAILY;BYHOUR=8", "intake times set the time of day"},
+		{"FREQ=DAILY;X-NAME=1", "not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			_, err := ParseRRule(tt.rule)
+			if err == nil {
+				t.Fatalf("ParseRRule(%q) succeeded", tt.rule)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseRRule(%q) = %q, want it to mention %q", tt.rule, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomScheduleExDates(t *testing.T) {
+	s := models.Schedule{
+		ID:        "1",
+		Frequency: "custom",
+		StartDate: day("2025-01-01"),
+		RRule:     "FREQ=WEEKLY;BYDAY=MO",
+		ExDates:   []string{"2025-01-13"},
+	}
+	times := []models.ScheduleTime{{ID: "1", IntakeTime: "08:00"}}
+
+	got, err := Occurrences(s, times, day("2025-01-01"), day("2025-02-01"), NewZones(time.UTC, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var days []string
+	for _, o := range got {
+		days = append(days, o.At.Format(dateLayout))
+	}
+	want := []string{"2025-01-06", "2025-01-20", "2025-01-27"}
+	if !slices.Equal(days, want) {
+		t.Errorf("days = %v, want %v", days, want)
+	}
+}
+
+func TestCustomScheduleInvalidRule(t *testing.T) {
+	s := models.Schedule{ID: "1", Frequency: "custom", StartDate: day("2025-01-01"), RRule: "FREQ=HOURLY"}
+	times := []models.ScheduleTime{{ID: "1", IntakeTime: "08:00"}}
+	if _, err := Occurrences(s, times, day("2025-01-01"), day("2025-02-01"), NewZones(time.UTC, nil)); err == nil {
+		t.Error("Occurrences accepted an invalid RRULE")
+	}
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"pillTickr-backend/store"
+)
+
+const DefaultSweepInterval = 5 * time.Minute
+
+// Sweeper marks pending reminders as missed once their schedule's grace window has passed
+type Sweeper struct {
+	store    store.Store
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewSweeper creates a sweeper that runs every interval
+func NewSweeper(st store.Store, interval time.Duration) *Sweeper {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+	return &Sweeper{
+		store:    st,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run sweeps immediately and then on every tick until ctx is done
+func (s *Sweeper) Run(ctx context.Context) {
+	slog.Info("Missed-dose sweeper started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SweepMissed(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Missed-dose sweep failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Missed-dose sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SweepMissed flips overdue pending reminders to 'missed' and returns how many changed
+func (s *Sweeper) SweepMissed(ctx context.Context) (int, error) {
+	now := s.now().UTC()
+
+	// Grace windows differ per schedule, so the cutoff is applied here rather
+	// than in SQL; only reminders already past due are candidates
+	candidates, err := s.store.Reminders().ListOverdueReminders(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var overdue []string
+	for _, c := range candidates {
+		if now.After(c.Due.Add(time.Duration(c.GraceMinutes) * time.Minute)) {
+			overdue = append(overdue, c.ID)
+		}
+	}
+	if len(overdue) == 0 {
+		return 0, nil
+	}
+
+	missed := 0
+	err = s.store.WithTx(ctx, func(tx store.Store) error {
+		for _, id := range overdue {
+			// MarkMissed re-checks the status: the user may have marked it taken meanwhile
+			changed, err := tx.Reminders().MarkMissed(ctx, id)
+			if err != nil {
+				return err
+			}
+			if changed {
+				missed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Info("Reminders marked missed", "count", missed)
+	return missed, nil
+}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+// Zones tells which time zone a user's intake times are read in on each
+// calendar day: their home zone, except on the days of a trip that adopts the
+// destination's wall clock
+type Zones struct {
+	Home *time.Location
+	trip *models.TravelPlan
+	away *time.Location
+}
+
+// NewZones returns the zones of a user living in home with an optional trip.
+// A trip that keeps home time, or whose zone cannot be loaded, changes nothing.
+func NewZones(home *time.Location, trip *models.TravelPlan) Zones {
+	z := Zones{Home: home}
+	if trip == nil || trip.Policy != models.TravelAdoptLocal {
+		return z
+	}
+	if away, err := models.LoadTimeZone(trip.TimeZone); err == nil {
+		z.trip, z.away = trip, away
+	}
+	return z
+}
+
+// traveling reports whether day's calendar date falls within the trip
+func (z Zones) traveling(day time.Time) bool {
+	if z.away == nil {
+		return false
+	}
+	date := civilDate(day)
+	return !date.Before(civilDate(z.trip.StartDate)) && !date.After(civilDate(z.trip.EndDate))
+}
+
+// On returns the time zone intake times on day's calendar date are read in
+func (z Zones) On(day time.Time) *time.Location {
+	if z.traveling(day) {
+		return z.away
+	}
+	return z.Home
+}
+
+// In returns t on the clock the user follows at that moment
+func (z Zones) In(t time.Time) time.Time {
+	if z.away != nil {
+		if local := t.In(z.away); z.traveling(local) {
+			return local
+		}
+	}
+	return t.In(z.Home)
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pillTickr-backend/authz"
+	"pillTickr-backend/db"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// rebound passes queries written with ? placeholders to q in the dialect's form
+type rebound struct {
+	q       querier
+	dialect db.Dialect
+}
+
+func (r rebound) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.q.ExecContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+func (r rebound) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.q.QueryContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+func (r rebound) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.q.QueryRowContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+// sqlStore implements every repository interface on top of database/sql.
+// The method sets do not overlap, so one value serves them all. Queries are
+// written once in SQL that SQLite and Postgres both accept.
+type sqlStore struct {
+	db      *sql.DB
+	dialect db.Dialect
+	q       querier
+	inTx    bool
+}
+
+// New returns a Store backed by a database opened with db.Open
+func New(conn *sql.DB, dialect db.Dialect) Store {
+	return &sqlStore{db: conn, dialect: dialect, q: rebound{conn, dialect}}
+}
+
+func (s *sqlStore) Users() UserStore         { return s }
+func (s *sqlStore) Medicines() MedicineStore { return s }
+func (s *sqlStore) Schedules() ScheduleStore { return s }
+func (s *sqlStore) Reminders() ReminderStore { return s }
+
+func (s *sqlStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqlStore{db: s.db, dialect: s.dialect, q: rebound{tx, s.dialect}, inTx: true}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ownerQueries resolve the owning user of each resource through the
+// users → medicines → schedules → schedule_times/reminders chain
+var ownerQueries = map[authz.Resource]string{
+	authz.Medicine: `SELECT user_id FROM medicines WHERE medicine_id = ?`,
+	authz.Schedule: `SELECT m.user_id
+		FROM schedules s
+		INNER JOIN medicines m ON s.medicine_id = m.medicine_id
+		WHERE s.schedule_id = ?`,
+	authz.ScheduleTime: `SELECT m.user_id
+		FROM schedule_times st
+		INNER JOIN schedules s ON st.schedule_id = s.schedule_id
+		INNER JOIN medicines m ON s.medicine_id = m.medicine_id
+		WHERE st.time_id = ?`,
+	authz.Reminder: `SELECT m.user_id
+		FROM reminders r
+		INNER JOIN schedules s ON r.schedule_id = s.schedule_id
+		INNER JOIN medicines m ON s.medicine_id = m.medicine_id
+		WHERE r.reminder_id = ?`,
+}
+
+func (s *sqlStore) OwnerOf(ctx context.Context, resource authz.Resource, id string) (string, error) {
+	query, ok := ownerQueries[resource]
+	if !ok {
+		return "", fmt.Errorf("unknown resource %q", resource)
+	}
+
+	// IDs are integers; anything else cannot exist
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return "", authz.ErrNotFound
+	}
+
+	var owner string
+	err := s.q.QueryRowContext(ctx, query, id).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", authz.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return owner, nil
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// pgUniqueViolation is the Postgres SQLSTATE for unique_violation
+const pgUniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a unique constraint failure
+func isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// requireOneRow returns ErrNotFound unless the statement changed a row
+func requireOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// nullString stores "" as NULL
+func nullString(v string) any {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+// nullInt stores 0 as NULL
+func nullInt(v int) any {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// formatTime renders an optional instant for a timestamp column, in UTC
+func formatTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"pillTickr-backend/authz"
+	"pillTickr-backend/crypto"
+	"pillTickr-backend/models"
+)
+
+// Dose amounts are health data like medicines.dosage: they are encrypted with
+// the owner's data key, both on dose steps and on the reminders carrying them.
+
+// scheduleKey returns the data key of the user owning a schedule, creating it
+// on first use when create is set
+func (s *sqlStore) scheduleKey(ctx context.Context, scheduleID string, create bool) ([]byte, error) {
+	owner, err := s.OwnerOf(ctx, authz.Schedule, scheduleID)
+	if errors.Is(err, authz.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if create {
+		return s.ensureUserKey(ctx, owner)
+	}
+	return s.userKey(ctx, owner)
+}
+
+// openDose decrypts an optional dose read from the database
+func openDose(dek []byte, dose *string) (*string, error) {
+	if dose == nil {
+		return nil, nil
+	}
+	plain, err := crypto.DecryptDataField(dek, *dose)
+	if err != nil {
+		return nil, err
+	}
+	return &plain, nil
+}
+
+func (s *sqlStore) ListDoseSteps(ctx context.Context, scheduleID string) ([]models.DoseStep, error) {
+	dek, err := s.scheduleKey(ctx, scheduleID, false)
+	if errors.Is(err, ErrNotFound) {
+		return []models.DoseStep{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT step_id, schedule_id, start_date, end_date, dose
+		 FROM schedule_dose_steps WHERE schedule_id = ?
+		 ORDER BY start_date`, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := []models.DoseStep{}
+	for rows.Next() {
+		var st models.DoseStep
+		if err := rows.Scan(&st.ID, &st.ScheduleID, &st.StartDate, &st.EndDate, &st.Dose); err != nil {
+			return nil, err
+		}
+		if st.Dose, err = crypto.DecryptDataField(dek, st.Dose); err != nil {
+			return nil, err
+		}
+		steps = append(steps, st)
+	}
+	return steps, rows.Err()
+}
+
+func (s *sqlStore) ReplaceDoseSteps(ctx context.Context, scheduleID string, steps []models.DoseStep) ([]models.DoseStep, error) {
+	stored := make([]models.DoseStep, 0, len(steps))
+	err := s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		dek, err := tx.scheduleKey(ctx, scheduleID, true)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.q.ExecContext(ctx, `DELETE FROM schedule_dose_steps WHERE schedule_id = ?`, scheduleID); err != nil {
+			return err
+		}
+		for _, st := range steps {
+			st.ScheduleID = scheduleID
+			dose, err := crypto.EncryptDataField(dek, st.Dose)
+			if err != nil {
+				return err
+			}
+			err = tx.q.QueryRowContext(ctx,
+				`INSERT INTO schedule_dose_steps (schedule_id, start_date, end_date, dose)
+				 VALUES (?, ?, ?, ?) RETURNING step_id`,
+				scheduleID, st.StartDate.Format(dateLayout), formatDate(st.EndDate), dose,
+			).Scan(&st.ID)
+			if isUniqueViolation(err) {
+				return ErrConflict
+			}
+			if err != nil {
+				return err
+			}
+			stored = append(stored, st)
+		}
+		return nil
+	})
+	return stored, err
+}
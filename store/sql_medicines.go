@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pillTickr-backend/crypto"
+	"pillTickr-backend/models"
+)
+
+// Medicine name, description, dosage and instructions are health data: they
+// are encrypted with the owner's data key on the way into the medicines table
+// and decrypted on the way out.
+
+// sealedMedicine holds the encrypted column values of a medicine
+type sealedMedicine struct {
+	name, description, dosage, instructions any
+}
+
+// sealMedicine encrypts the sensitive fields of m with dek for writing
+func sealMedicine(m models.Medicine, dek []byte) (sealedMedicine, error) {
+	var sealed sealedMedicine
+	var err error
+	if sealed.name, err = crypto.EncryptDataField(dek, m.Name); err != nil {
+		return sealed, err
+	}
+	if sealed.description, err = sealOptional(dek, m.Description); err != nil {
+		return sealed, err
+	}
+	if sealed.dosage, err = sealOptional(dek, m.Dosage); err != nil {
+		return sealed, err
+	}
+	sealed.instructions, err = sealOptional(dek, m.Instructions)
+	return sealed, err
+}
+
+// sealedAs reports whether the stored columns of m already hold the sealed
+// values, as for fields that are empty and so never encrypted
+func sealedAs(m models.Medicine, sealed sealedMedicine) bool {
+	same := func(stored *string, v any) bool {
+		if stored == nil || v == nil {
+			return stored == nil && v == nil
+		}
+		return *stored == v
+	}
+	return same(&m.Name, sealed.name) && same(m.Description, sealed.description) &&
+		same(m.Dosage, sealed.dosage) && same(m.Instructions, sealed.instructions)
+}
+
+// sealOptional encrypts a nullable field, keeping NULL as NULL
+func sealOptional(dek []byte, v *string) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return crypto.EncryptDataField(dek, *v)
+}
+
+// openMedicine decrypts the sensitive fields of a medicine read from the
+// table; dek is the owner's data key, nil if they have none
+func openMedicine(m *models.Medicine, dek []byte) error {
+	var err error
+	if m.Name, err = crypto.DecryptDataField(dek, m.Name); err != nil {
+		return err
+	}
+	// Fresh strings rather than writes through the pointers, which may be shared
+	for _, field := range []**string{&m.Description, &m.Dosage, &m.Instructions} {
+		if *field == nil {
+			continue
+		}
+		plain, err := crypto.DecryptDataField(dek, **field)
+		if err != nil {
+			return err
+		}
+		*field = &plain
+	}
+	return nil
+}
+
+const medicineColumns = `medicine_id, user_id, name, description, dosage, instructions, created_at, archived_at`
+
+// scanMedicine reads a row selected with medicineColumns, still encrypted
+func scanMedicine(row interface{ Scan(...any) error }) (models.Medicine, error) {
+	var m models.Medicine
+	err := row.Scan(&m.ID, &m.UserID, &m.Name, &m.Description, &m.Dosage, &m.Instructions, &m.CreatedAt, &m.ArchivedAt)
+	return m, err
+}
+
+func (s *sqlStore) ListMedicines(ctx context.Context, userID string, includeArchived bool) ([]models.Medicine, error) {
+	dek, err := s.userKey(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + medicineColumns + ` FROM medicines WHERE user_id = ?`
+	if !includeArchived {
+		query += ` AND archived_at IS NULL`
+	}
+	rows, err := s.q.QueryContext(ctx, query+` ORDER BY medicine_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	medicines := []models.Medicine{}
+	for rows.Next() {
+		m, err := scanMedicine(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := openMedicine(&m, dek); err != nil {
+			return nil, err
+		}
+		medicines = append(medicines, m)
+	}
+	return medicines, rows.Err()
+}
+
+func (s *sqlStore) GetMedicine(ctx context.Context, id string) (models.Medicine, error) {
+	m, err := scanMedicine(s.q.QueryRowContext(ctx,
+		`SELECT `+medicineColumns+` FROM medicines WHERE medicine_id = ?`, id))
+	if err != nil {
+		return m, notFound(err)
+	}
+	dek, err := s.userKey(ctx, m.UserID)
+	if err != nil {
+		return m, err
+	}
+	return m, openMedicine(&m, dek)
+}
+
+func (s *sqlStore) CreateMedicine(ctx context.Context, m *models.Medicine) error {
+	dek, err := s.ensureUserKey(ctx, m.UserID)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealMedicine(*m, dek)
+	if err != nil {
+		return err
+	}
+	return s.q.QueryRowContext(ctx,
+		`INSERT INTO medicines (user_id, name, description, dosage, instructions, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?) RETURNING medicine_id, created_at`,
+		m.UserID, sealed.name, sealed.description, sealed.dosage, sealed.instructions, time.Now().UTC(),
+	).Scan(&m.ID, &m.CreatedAt)
+}
+
+func (s *sqlStore) UpdateMedicine(ctx context.Context, m models.Medicine) error {
+	dek, err := s.ensureUserKey(ctx, m.UserID)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealMedicine(m, dek)
+	if err != nil {
+		return err
+	}
+	var archivedAt any
+	if m.ArchivedAt != nil {
+		archivedAt = m.ArchivedAt.UTC()
+	}
+	res, err := s.q.ExecContext(ctx,
+		`UPDATE medicines SET name = ?, description = ?, dosage = ?, instructions = ?, archived_at = ?
+		 WHERE medicine_id = ?`,
+		sealed.name, sealed.description, sealed.dosage, sealed.instructions, archivedAt, m.ID)
+	if err != nil {
+		return err
+	}
+	return requireOneRow(res)
+}
+
+func (s *sqlStore) DeleteMedicine(ctx context.Context, id string) error {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM medicines WHERE medicine_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireOneRow(res)
+}
+
+func (s *sqlStore) IsMedicineArchived(ctx context.Context, id string) (bool, error) {
+	var archivedAt *time.Time
+	err := s.q.QueryRowContext(ctx, `SELECT archived_at FROM medicines WHERE medicine_id = ?`, id).Scan(&archivedAt)
+	if err != nil {
+		return false, notFound(err)
+	}
+	return archivedAt != nil, nil
+}
+
+// staleMedicineFilter matches rows with a sensitive field that is plaintext or
+// encrypted under a master key rather than the owner's data key. Empty values
+// are never encrypted, so they are not stale. substr keeps the prefix
+// comparison case-sensitive on both SQLite and Postgres, unlike LIKE.
+const staleMedicineFilter = `(name <> '' AND substr(name, 1, ?) <> ?)
+	OR (description IS NOT NULL AND description <> '' AND substr(description, 1, ?) <> ?)
+	OR (dosage IS NOT NULL AND dosage <> '' AND substr(dosage, 1, ?) <> ?)
+	OR (instructions IS NOT NULL AND instructions <> '' AND substr(instructions, 1, ?) <> ?)`
+
+func (s *sqlStore) ReencryptMedicines(ctx context.Context, limit int) (int, error) {
+	prefix := crypto.DataFieldPrefix()
+	n := len(prefix)
+
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT medicine_id, user_id, name, description, dosage, instructions
+		 FROM medicines
+		 WHERE `+staleMedicineFilter+`
+		 ORDER BY medicine_id
+		 LIMIT ?`,
+		n, prefix, n, prefix, n, prefix, n, prefix, limit)
+	if err != nil {
+		return 0, err
+	}
+	var stale []models.Medicine
+	for rows.Next() {
+		var m models.Medicine
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Name, &m.Description, &m.Dosage, &m.Instructions); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stale = append(stale, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	keys := s.newUserKeyCache(true)
+	changed := 0
+	for _, stored := range stale {
+		dek, err := keys.get(ctx, stored.UserID)
+		if err != nil {
+			return changed, err
+		}
+		m := stored
+		if err := openMedicine(&m, dek); err != nil {
+			return changed, fmt.Errorf("medicine %s: %w", m.ID, err)
+		}
+		sealed, err := sealMedicine(m, dek)
+		if err != nil {
+			return changed, err
+		}
+		if sealedAs(stored, sealed) {
+			continue
+		}
+
+		// Only replace the values that were read, so a concurrent edit is never
+		// overwritten with stale data; such a row is picked up again next batch
+		res, err := s.q.ExecContext(ctx,
+			`UPDATE medicines SET name = ?, description = ?, dosage = ?, instructions = ?
+			 WHERE medicine_id = ?
+			   AND name = ?
+			   AND description IS NOT DISTINCT FROM ?
+			   AND dosage IS NOT DISTINCT FROM ?
+			   AND instructions IS NOT DISTINCT FROM ?`,
+			sealed.name, sealed.description, sealed.dosage, sealed.instructions, m.ID,
+			stored.Name, stored.Description, stored.Dosage, stored.Instructions)
+		if err != nil {
+			return changed, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			changed++
+		}
+	}
+	return changed, nil
+}
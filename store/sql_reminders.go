@@ -0,0 +1,353 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"pillTickr-backend/crypto"
+	"pillTickr-backend/models"
+)
+
+// withOutcome fills in the reminder's outcome from its schedule's grace window
+func withOutcome(r models.Reminder, graceMinutes int) models.Reminder {
+	r.Outcome = models.ReminderOutcome(r.Status, r.ReminderDatetime, r.TakenAt, time.Duration(graceMinutes)*time.Minute)
+	return r
+}
+
+func (s *sqlStore) ListReminders(ctx context.Context, userID string) ([]models.Reminder, error) {
+	dek, err := s.userKey(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT r.reminder_id, r.schedule_id, r.time_id, r.reminder_datetime, r.status, r.taken_at, r.source, r.dose, r.version_id,
+		       s.grace_minutes
+		FROM reminders r
+		INNER JOIN schedules s ON r.schedule_id = s.schedule_id
+		INNER JOIN medicines m ON s.medicine_id = m.medicine_id
+		WHERE m.user_id = ?
+		ORDER BY r.reminder_datetime`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []models.Reminder{}
+	for rows.Next() {
+		var r models.Reminder
+		var graceMinutes int
+		if err := rows.Scan(&r.ID, &r.ScheduleID, &r.TimeID, &r.ReminderDatetime, &r.Status, &r.TakenAt, &r.Source, &r.Dose, &r.VersionID, &graceMinutes); err != nil {
+			return nil, err
+		}
+		if r.Dose, err = openDose(dek, r.Dose); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, withOutcome(r, graceMinutes))
+	}
+	return reminders, rows.Err()
+}
+
+func (s *sqlStore) GetReminder(ctx context.Context, id string) (models.Reminder, error) {
+	var r models.Reminder
+	var graceMinutes int
+	var userID string
+	err := s.q.QueryRowContext(ctx, `
+		SELECT r.reminder_id, r.schedule_id, r.time_id, r.reminder_datetime, r.status, r.taken_at, r.source, r.dose, r.version_id,
+		       s.grace_minutes, m.user_id
+		FROM reminders r
+		INNER JOIN schedules s ON r.schedule_id = s.schedule_id
+		INNER JOIN medicines m ON s.medicine_id = m.medicine_id
+		WHERE r.reminder_id = ?`, id,
+	).Scan(&r.ID, &r.ScheduleID, &r.TimeID, &r.ReminderDatetime, &r.Status, &r.TakenAt, &r.Source, &r.Dose, &r.VersionID,
+		&graceMinutes, &userID)
+	if err != nil {
+		return r, notFound(err)
+	}
+	if r.Dose != nil {
+		dek, err := s.userKey(ctx, userID)
+		if err != nil {
+			return r, err
+		}
+		if r.Dose, err = openDose(dek, r.Dose); err != nil {
+			return r, err
+		}
+	}
+	return withOutcome(r, graceMinutes), nil
+}
+
+func (s *sqlStore) LastTakenAt(ctx context.Context, scheduleID string) (*time.Time, error) {
+	// ORDER BY rather than MAX keeps the column type, so SQLite returns a time
+	var takenAt time.Time
+	err := s.q.QueryRowContext(ctx, `
+		SELECT taken_at FROM reminders
+		WHERE schedule_id = ? AND status = 'taken' AND taken_at IS NOT NULL
+		ORDER BY taken_at DESC
+		LIMIT 1`, scheduleID).Scan(&takenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &takenAt, nil
+}
+
+func (s *sqlStore) CountPlannedDoses(ctx context.Context, scheduleID string) (int, error) {
+	var n int
+	err := s.q.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM reminders
+		WHERE schedule_id = ? AND status IN ('taken', 'pending')`, scheduleID).Scan(&n)
+	return n, err
+}
+
+func (s *sqlStore) ListReminderHistory(ctx context.Context, userID string, from, to time.Time) ([]ReminderHistory, error) {
+	dek, err := s.userKey(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT m.medicine_id, m.name, r.reminder_id, r.schedule_id, r.reminder_datetime, r.status, r.taken_at, s.grace_minutes
+		FROM reminders r
+		INNER JOIN schedules s ON r.schedule_id = s.schedule_id
+		INNER JOIN medicines m ON s.medicine_id = m.medicine_id
+		WHERE m.user_id = ? AND r.reminder_datetime >= ? AND r.reminder_datetime < ?
+		  AND (r.status = 'taken' OR NOT EXISTS (
+		      SELECT 1 FROM schedule_pauses p
+		      WHERE p.schedule_id = r.schedule_id AND p.paused_at <= r.reminder_datetime
+		        AND (p.resumed_at IS NULL OR p.resumed_at > r.reminder_datetime)))
+		ORDER BY r.reminder_datetime`,
+		userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []ReminderHistory
+	for rows.Next() {
+		var h ReminderHistory
+		var graceMinutes int
+		err := rows.Scan(&h.MedicineID, &h.MedicineName, &h.Reminder.ID, &h.Reminder.ScheduleID,
+			&h.Reminder.ReminderDatetime, &h.Reminder.Status, &h.Reminder.TakenAt, &graceMinutes)
+		if err != nil {
+			return nil, err
+		}
+		if h.MedicineName, err = crypto.DecryptDataField(dek, h.MedicineName); err != nil {
+			return nil, err
+		}
+		h.Reminder = withOutcome(h.Reminder, graceMinutes)
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+func (s *sqlStore) CreateReminder(ctx context.Context, r *models.Reminder) error {
+	return s.q.QueryRowContext(ctx, `
+		INSERT INTO reminders (schedule_id, reminder_datetime, status, taken_at)
+		VALUES (?, ?, ?, ?) RETURNING reminder_id`,
+		r.ScheduleID, r.ReminderDatetime.UTC(), r.Status, formatTime(r.TakenAt),
+	).Scan(&r.ID)
+}
+
+func (s *sqlStore) UpdateReminder(ctx context.Context, r models.Reminder) error {
+	res, err := s.q.ExecContext(ctx, `
+		UPDATE reminders
+		SET reminder_datetime = ?, status = ?, taken_at = ?
+		WHERE reminder_id = ?`,
+		r.ReminderDatetime.UTC(), r.Status, formatTime(r.TakenAt), r.ID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	return requireOneRow(res)
+}
+
+func (s *sqlStore) DeleteReminder(ctx context.Context, id string) error {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM reminders WHERE reminder_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireOneRow(res)
+}
+
+func (s *sqlStore) InsertGeneratedReminder(ctx context.Context, r models.Reminder) (bool, error) {
+	var dose any
+	if r.Dose != nil {
+		dek, err := s.scheduleKey(ctx, r.ScheduleID, true)
+		if err != nil {
+			return false, err
+		}
+		if dose, err = crypto.EncryptDataField(dek, *r.Dose); err != nil {
+			return false, err
+		}
+	}
+	res, err := s.q.ExecContext(ctx, `
+		INSERT INTO reminders (schedule_id, time_id, reminder_datetime, status, source, dose, version_id)
+		VALUES (?, ?, ?, 'pending', 'generated', ?, ?)
+		ON CONFLICT (schedule_id, reminder_datetime) WHERE source = 'generated' DO NOTHING`,
+		r.ScheduleID, r.TimeID, r.ReminderDatetime.UTC(), dose, r.VersionID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *sqlStore) DeleteFutureGeneratedReminders(ctx context.Context, scheduleID string, from time.Time) error {
+	_, err := s.q.ExecContext(ctx, `
+		DELETE FROM reminders
+		WHERE schedule_id = ? AND source = 'generated' AND status = 'pending' AND reminder_datetime >= ?`,
+		scheduleID, from.UTC())
+	return err
+}
+
+func (s *sqlStore) DeleteFutureMedicineReminders(ctx context.Context, medicineID string, from time.Time) error {
+	_, err := s.q.ExecContext(ctx, `
+		DELETE FROM reminders
+		WHERE status = 'pending' AND reminder_datetime >= ?
+		  AND schedule_id IN (SELECT schedule_id FROM schedules WHERE medicine_id = ?)`,
+		from.UTC(), medicineID)
+	return err
+}
+
+func (s *sqlStore) ListOverdueReminders(ctx context.Context, now time.Time) ([]OverdueReminder, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT r.reminder_id, r.reminder_datetime, s.grace_minutes
+		FROM reminders r
+		INNER JOIN schedules s ON r.schedule_id = s.schedule_id
+		WHERE r.status = 'pending' AND r.reminder_datetime <= ?`, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overdue []OverdueReminder
+	for rows.Next() {
+		var o OverdueReminder
+		if err := rows.Scan(&o.ID, &o.Due, &o.GraceMinutes); err != nil {
+			return nil, err
+		}
+		overdue = append(overdue, o)
+	}
+	return overdue, rows.Err()
+}
+
+func (s *sqlStore) MarkMissed(ctx context.Context, id string) (bool, error) {
+	res, err := s.q.ExecContext(ctx, `UPDATE reminders SET status = 'missed' WHERE reminder_id = ? AND status = 'pending'`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *sqlStore) ListDueForNotification(ctx context.Context, since, now time.Time, maxAttempts, limit int) ([]DueReminder, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT r.reminder_id, r.schedule_id, r.reminder_datetime, r.status, r.dose,
+		       m.medicine_id, m.name, m.dosage, m.instructions,
+		       u.user_id, u.name, u.email
+		FROM reminders r
+		INNER JOIN schedules s ON r.schedule_id = s.schedule_id
+		INNER JOIN medicines m ON s.medicine_id = m.medicine_id
+		INNER JOIN users u ON m.user_id = u.user_id
+		WHERE r.status = 'pending'
+		  AND r.notified_at IS NULL
+		  AND r.reminder_datetime >= ? AND r.reminder_datetime <= ?
+		  AND r.notify_attempts < ?
+		  AND (r.next_notify_at IS NULL OR r.next_notify_at <= ?)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM schedule_pauses p
+		      WHERE p.schedule_id = r.schedule_id AND p.paused_at <= r.reminder_datetime
+		        AND (p.resumed_at IS NULL OR p.resumed_at > r.reminder_datetime))
+		ORDER BY r.reminder_datetime
+		LIMIT ?`,
+		since.UTC(), now.UTC(), maxAttempts, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []DueReminder
+	for rows.Next() {
+		var d DueReminder
+		err := rows.Scan(&d.Reminder.ID, &d.Reminder.ScheduleID, &d.Reminder.ReminderDatetime, &d.Reminder.Status, &d.Reminder.Dose,
+			&d.Medicine.ID, &d.Medicine.Name, &d.Medicine.Dosage, &d.Medicine.Instructions,
+			&d.User.ID, &d.User.Name, &d.User.Email)
+		if err != nil {
+			return nil, err
+		}
+		d.Medicine.UserID = d.User.ID
+		due = append(due, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Decrypted once the rows are closed, as loading data keys needs the connection
+	keys := s.newUserKeyCache(false)
+	for i := range due {
+		dek, err := keys.get(ctx, due[i].User.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := openMedicine(&due[i].Medicine, dek); err != nil {
+			return nil, err
+		}
+		if due[i].Reminder.Dose, err = openDose(dek, due[i].Reminder.Dose); err != nil {
+			return nil, err
+		}
+	}
+	return due, nil
+}
+
+func (s *sqlStore) ClaimForNotification(ctx context.Context, id string, now, leaseUntil time.Time) (int, bool, error) {
+	var attempts int
+	err := s.q.QueryRowContext(ctx, `
+		UPDATE reminders
+		SET next_notify_at = ?, notify_attempts = notify_attempts + 1
+		WHERE reminder_id = ?
+		  AND status = 'pending'
+		  AND notified_at IS NULL
+		  AND (next_notify_at IS NULL OR next_notify_at <= ?)
+		RETURNING notify_attempts`,
+		leaseUntil.UTC(), id, now.UTC(),
+	).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return attempts, true, nil
+}
+
+func (s *sqlStore) RecordNotificationAttempt(ctx context.Context, id, notifier string, at time.Time, sendErr error, retryAt time.Time) error {
+	return s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		var errText sql.NullString
+		if sendErr != nil {
+			errText = sql.NullString{String: sendErr.Error(), Valid: true}
+		}
+		_, err := tx.q.ExecContext(ctx, `
+			INSERT INTO notification_attempts (reminder_id, notifier, attempted_at, success, error)
+			VALUES (?, ?, ?, ?, ?)`,
+			id, notifier, at.UTC(), sendErr == nil, errText)
+		if err != nil {
+			return err
+		}
+
+		if sendErr == nil {
+			_, err = tx.q.ExecContext(ctx, `UPDATE reminders SET notified_at = ?, next_notify_at = NULL WHERE reminder_id = ?`, at.UTC(), id)
+		} else {
+			_, err = tx.q.ExecContext(ctx, `UPDATE reminders SET next_notify_at = ? WHERE reminder_id = ?`, retryAt.UTC(), id)
+		}
+		return err
+	})
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+const pauseColumns = `pause_id, schedule_id, paused_at, resumed_at`
+
+// scanPause reads a row selected with pauseColumns
+func scanPause(row interface{ Scan(...any) error }) (models.SchedulePause, error) {
+	var p models.SchedulePause
+	err := row.Scan(&p.ID, &p.ScheduleID, &p.PausedAt, &p.ResumedAt)
+	return p, err
+}
+
+func (s *sqlStore) ListPauses(ctx context.Context, scheduleID string) ([]models.SchedulePause, error) {
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT `+pauseColumns+` FROM schedule_pauses WHERE schedule_id = ? ORDER BY paused_at`, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pauses := []models.SchedulePause{}
+	for rows.Next() {
+		p, err := scanPause(rows)
+		if err != nil {
+			return nil, err
+		}
+		pauses = append(pauses, p)
+	}
+	return pauses, rows.Err()
+}
+
+// pauseInEffect returns the schedule's pause covering at; sql.ErrNoRows if none
+func (s *sqlStore) pauseInEffect(ctx context.Context, scheduleID string, at time.Time) (models.SchedulePause, error) {
+	return scanPause(s.q.QueryRowContext(ctx,
+		`SELECT `+pauseColumns+` FROM schedule_pauses
+		 WHERE schedule_id = ? AND paused_at <= ? AND (resumed_at IS NULL OR resumed_at > ?)`,
+		scheduleID, at.UTC(), at.UTC()))
+}
+
+func (s *sqlStore) PauseSchedule(ctx context.Context, scheduleID string, at time.Time, resumeAt *time.Time) (models.SchedulePause, error) {
+	p := models.SchedulePause{ScheduleID: scheduleID, PausedAt: at.UTC(), ResumedAt: resumeAt}
+	err := s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		_, err := tx.pauseInEffect(ctx, scheduleID, at)
+		if err == nil {
+			return ErrConflict
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		return tx.q.QueryRowContext(ctx,
+			`INSERT INTO schedule_pauses (schedule_id, paused_at, resumed_at) VALUES (?, ?, ?) RETURNING pause_id`,
+			scheduleID, p.PausedAt, formatTime(resumeAt),
+		).Scan(&p.ID)
+	})
+	return p, err
+}
+
+func (s *sqlStore) ResumeSchedule(ctx context.Context, scheduleID string, at time.Time) (models.SchedulePause, error) {
+	var p models.SchedulePause
+	err := s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		var err error
+		p, err = tx.pauseInEffect(ctx, scheduleID, at)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		resumedAt := at.UTC()
+		p.ResumedAt = &resumedAt
+		_, err = tx.q.ExecContext(ctx,
+			`UPDATE schedule_pauses SET resumed_at = ? WHERE pause_id = ?`, resumedAt, p.ID)
+		return err
+	})
+	return p, err
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+const versionColumns = `version_id, schedule_id, version, effective_from, start_date, end_date, frequency, times_per_day,
+	weekdays, rrule, exdates, interval_hours, first_dose_at, cycle_active_days, cycle_rest_days, total_doses,
+	grace_minutes, intake_times,
+	(SELECT COUNT(*) FROM reminders r WHERE r.version_id = schedule_versions.version_id)`
+
+// scanVersion reads a row selected with versionColumns
+func scanVersion(row interface{ Scan(...any) error }) (models.ScheduleVersion, error) {
+	var v models.ScheduleVersion
+	d := &v.Definition
+	var rrule, exdates, intakeTimes *string
+	var intervalHours, cycleActiveDays, cycleRestDays, totalDoses *int
+	err := row.Scan(&v.ID, &v.ScheduleID, &v.Version, &v.EffectiveFrom, &d.StartDate, &d.EndDate, &d.Frequency,
+		&d.TimesPerDay, &d.Weekdays, &rrule, &exdates, &intervalHours, &d.FirstDoseAt,
+		&cycleActiveDays, &cycleRestDays, &totalDoses, &d.GraceMinutes, &intakeTimes, &v.Reminders)
+	if rrule != nil {
+		d.RRule = *rrule
+	}
+	if exdates != nil && *exdates != "" {
+		d.ExDates = strings.Split(*exdates, ",")
+	}
+	if intervalHours != nil {
+		d.IntervalHours = *intervalHours
+	}
+	if cycleActiveDays != nil {
+		d.CycleActiveDays = *cycleActiveDays
+	}
+	if cycleRestDays != nil {
+		d.CycleRestDays = *cycleRestDays
+	}
+	if totalDoses != nil {
+		d.TotalDoses = *totalDoses
+	}
+	d.IntakeTimes = []string{}
+	if intakeTimes != nil && *intakeTimes != "" {
+		d.IntakeTimes = strings.Split(*intakeTimes, ",")
+	}
+	return v, err
+}
+
+func (s *sqlStore) ListScheduleVersions(ctx context.Context, scheduleID string) ([]models.ScheduleVersion, error) {
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT `+versionColumns+` FROM schedule_versions WHERE schedule_id = ? ORDER BY version`, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []models.ScheduleVersion{}
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func (s *sqlStore) CurrentScheduleVersion(ctx context.Context, scheduleID string) (models.ScheduleVersion, error) {
+	v, err := scanVersion(s.q.QueryRowContext(ctx,
+		`SELECT `+versionColumns+` FROM schedule_versions WHERE schedule_id = ?
+		 ORDER BY version DESC LIMIT 1`, scheduleID))
+	return v, notFound(err)
+}
+
+func (s *sqlStore) RecordScheduleVersion(ctx context.Context, scheduleID string, at time.Time) (models.ScheduleVersion, error) {
+	var v models.ScheduleVersion
+	err := s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		sc, err := tx.GetSchedule(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		times, err := tx.ListScheduleTimes(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		def := sc.Definition(times)
+
+		current, err := tx.CurrentScheduleVersion(ctx, scheduleID)
+		switch {
+		case err == nil:
+			if len(def.Changes(current.Definition)) == 0 {
+				v = current
+				return nil
+			}
+			effectiveFrom := at.UTC()
+			v = models.ScheduleVersion{ScheduleID: scheduleID, Version: current.Version + 1, EffectiveFrom: &effectiveFrom}
+		case errors.Is(err, ErrNotFound):
+			v = models.ScheduleVersion{ScheduleID: scheduleID, Version: 1}
+		default:
+			return err
+		}
+		v.Definition = def
+
+		return tx.q.QueryRowContext(ctx,
+			`INSERT INTO schedule_versions (schedule_id, version, effective_from, start_date, end_date, frequency,
+			     times_per_day, weekdays, rrule, exdates, interval_hours, first_dose_at, cycle_active_days,
+			     cycle_rest_days, total_doses, grace_minutes, intake_times)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING version_id`,
+			scheduleID, v.Version, formatTime(v.EffectiveFrom), def.StartDate.Format(dateLayout), formatDate(def.EndDate),
+			def.Frequency, def.TimesPerDay, int(def.Weekdays), nullString(def.RRule), nullString(strings.Join(def.ExDates, ",")),
+			nullInt(def.IntervalHours), formatTime(def.FirstDoseAt), nullInt(def.CycleActiveDays), nullInt(def.CycleRestDays),
+			nullInt(def.TotalDoses), def.GraceMinutes, nullString(strings.Join(def.IntakeTimes, ",")),
+		).Scan(&v.ID)
+	})
+	return v, err
+}
@@ -0,0 +1,222 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+// dateLayout is how DATE columns are stored
+const dateLayout = "2006-01-02"
+
+// formatDate renders an optional date for a DATE column
+func formatDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format(dateLayout)
+}
+
+const scheduleColumns = `schedule_id, medicine_id, start_date, end_date, frequency, times_per_day, weekdays, rrule, exdates,
+	interval_hours, first_dose_at, cycle_active_days, cycle_rest_days, total_doses, grace_minutes, generated_until,
+	(SELECT COUNT(*) FROM reminders r WHERE r.schedule_id = schedules.schedule_id AND r.status = 'taken')`
+
+// scanSchedule reads a row selected with scheduleColumns
+func scanSchedule(row interface{ Scan(...any) error }) (models.Schedule, error) {
+	var sc models.Schedule
+	var rrule, exdates *string
+	var intervalHours, cycleActiveDays, cycleRestDays, totalDoses *int
+	var taken int
+	err := row.Scan(&sc.ID, &sc.MedicineID, &sc.StartDate, &sc.EndDate, &sc.Frequency,
+		&sc.TimesPerDay, &sc.Weekdays, &rrule, &exdates,
+		&intervalHours, &sc.FirstDoseAt, &cycleActiveDays, &cycleRestDays, &totalDoses,
+		&sc.GraceMinutes, &sc.GeneratedUntil, &taken)
+	if intervalHours != nil {
+		sc.IntervalHours = *intervalHours
+	}
+	if cycleActiveDays != nil {
+		sc.CycleActiveDays = *cycleActiveDays
+	}
+	if cycleRestDays != nil {
+		sc.CycleRestDays = *cycleRestDays
+	}
+	if totalDoses != nil {
+		sc.TotalDoses = *totalDoses
+		remaining := max(sc.TotalDoses-taken, 0)
+		sc.RemainingDoses = &remaining
+	}
+	if rrule != nil {
+		sc.RRule = *rrule
+	}
+	if exdates != nil && *exdates != "" {
+		sc.ExDates = strings.Split(*exdates, ",")
+	}
+	return sc, err
+}
+
+func (s *sqlStore) ListSchedules(ctx context.Context, medicineID string) ([]models.Schedule, error) {
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT `+scheduleColumns+` FROM schedules WHERE medicine_id = ? ORDER BY schedule_id`, medicineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []models.Schedule{}
+	for rows.Next() {
+		sc, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, sc)
+	}
+	return schedules, rows.Err()
+}
+
+func (s *sqlStore) GetSchedule(ctx context.Context, id string) (models.Schedule, error) {
+	sc, err := scanSchedule(s.q.QueryRowContext(ctx,
+		`SELECT `+scheduleColumns+` FROM schedules WHERE schedule_id = ?`, id))
+	return sc, notFound(err)
+}
+
+func (s *sqlStore) CreateSchedule(ctx context.Context, sc *models.Schedule) error {
+	return s.q.QueryRowContext(ctx,
+		`INSERT INTO schedules (medicine_id, start_date, end_date, frequency, times_per_day, weekdays, rrule, exdates,
+		                        interval_hours, first_dose_at, cycle_active_days, cycle_rest_days, total_doses, grace_minutes)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING schedule_id`,
+		sc.MedicineID, sc.StartDate.Format(dateLayout), formatDate(sc.EndDate), sc.Frequency, sc.TimesPerDay, int(sc.Weekdays),
+		nullString(sc.RRule), nullString(strings.Join(sc.ExDates, ",")),
+		nullInt(sc.IntervalHours), formatTime(sc.FirstDoseAt), nullInt(sc.CycleActiveDays), nullInt(sc.CycleRestDays), nullInt(sc.TotalDoses), sc.GraceMinutes,
+	).Scan(&sc.ID)
+}
+
+func (s *sqlStore) UpdateSchedule(ctx context.Context, sc models.Schedule) error {
+	res, err := s.q.ExecContext(ctx,
+		`UPDATE schedules SET start_date = ?, end_date = ?, frequency = ?, times_per_day = ?, weekdays = ?,
+		     rrule = ?, exdates = ?, interval_hours = ?, first_dose_at = ?,
+		     cycle_active_days = ?, cycle_rest_days = ?, total_doses = ?, grace_minutes = ?
+		 WHERE schedule_id = ?`,
+		sc.StartDate.Format(dateLayout), formatDate(sc.EndDate), sc.Frequency, sc.TimesPerDay, int(sc.Weekdays),
+		nullString(sc.RRule), nullString(strings.Join(sc.ExDates, ",")),
+		nullInt(sc.IntervalHours), formatTime(sc.FirstDoseAt),
+		nullInt(sc.CycleActiveDays), nullInt(sc.CycleRestDays), nullInt(sc.TotalDoses), sc.GraceMinutes, sc.ID)
+	if err != nil {
+		return err
+	}
+	return requireOneRow(res)
+}
+
+func (s *sqlStore) DeleteSchedule(ctx context.Context, id string) error {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM schedules WHERE schedule_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireOneRow(res)
+}
+
+func (s *sqlStore) ListScheduleTimes(ctx context.Context, scheduleID string) ([]models.ScheduleTime, error) {
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT time_id, schedule_id, intake_time
+		 FROM schedule_times WHERE schedule_id = ?
+		 ORDER BY intake_time`, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	times := []models.ScheduleTime{}
+	for rows.Next() {
+		var t models.ScheduleTime
+		if err := rows.Scan(&t.ID, &t.ScheduleID, &t.IntakeTime); err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, rows.Err()
+}
+
+func (s *sqlStore) CreateScheduleTime(ctx context.Context, t *models.ScheduleTime) error {
+	return s.q.QueryRowContext(ctx,
+		`INSERT INTO schedule_times (schedule_id, intake_time) VALUES (?, ?) RETURNING time_id`,
+		t.ScheduleID, t.IntakeTime,
+	).Scan(&t.ID)
+}
+
+func (s *sqlStore) DeleteScheduleTime(ctx context.Context, scheduleID, timeID string) error {
+	res, err := s.q.ExecContext(ctx,
+		`DELETE FROM schedule_times WHERE time_id = ? AND schedule_id = ?`, timeID, scheduleID)
+	if err != nil {
+		return err
+	}
+	return requireOneRow(res)
+}
+
+func (s *sqlStore) ReplaceScheduleTimes(ctx context.Context, scheduleID string, intakeTimes []string) ([]models.ScheduleTime, error) {
+	var times []models.ScheduleTime
+	err := s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		existing, err := tx.ListScheduleTimes(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		// Times that stay keep their IDs, so reminders already generated from them still point at them
+		kept := make(map[string]models.ScheduleTime, len(existing))
+		for _, t := range existing {
+			kept[t.IntakeTime] = t
+		}
+
+		times = make([]models.ScheduleTime, 0, len(intakeTimes))
+		for _, intake := range intakeTimes {
+			if t, ok := kept[intake]; ok {
+				delete(kept, intake)
+				times = append(times, t)
+				continue
+			}
+			t := models.ScheduleTime{ScheduleID: scheduleID, IntakeTime: intake}
+			if err := tx.CreateScheduleTime(ctx, &t); err != nil {
+				return err
+			}
+			times = append(times, t)
+		}
+		for _, t := range kept {
+			if err := tx.DeleteScheduleTime(ctx, scheduleID, t.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return times, err
+}
+
+func (s *sqlStore) ListSchedulesToGenerate(ctx context.Context, today, horizon time.Time) ([]string, error) {
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT s.schedule_id
+		 FROM schedules s
+		 INNER JOIN medicines m ON s.medicine_id = m.medicine_id
+		 WHERE m.archived_at IS NULL
+		   AND (s.end_date IS NULL OR s.end_date >= ?)
+		   AND (s.generated_until IS NULL OR s.generated_until < ?)`,
+		today.Format(dateLayout), horizon.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *sqlStore) SetGeneratedUntil(ctx context.Context, scheduleID string, until time.Time) error {
+	_, err := s.q.ExecContext(ctx, `UPDATE schedules SET generated_until = ? WHERE schedule_id = ?`, until.UTC(), scheduleID)
+	return err
+}
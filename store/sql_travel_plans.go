@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+
+	"pillTickr-backend/models"
+)
+
+func (s *sqlStore) GetTravelPlan(ctx context.Context, userID string) (models.TravelPlan, error) {
+	var p models.TravelPlan
+	err := s.q.QueryRowContext(ctx,
+		`SELECT user_id, time_zone, start_date, end_date, policy
+		 FROM travel_plans WHERE user_id = ?`, userID,
+	).Scan(&p.UserID, &p.TimeZone, &p.StartDate, &p.EndDate, &p.Policy)
+	return p, notFound(err)
+}
+
+func (s *sqlStore) SetTravelPlan(ctx context.Context, p models.TravelPlan) error {
+	_, err := s.q.ExecContext(ctx,
+		`INSERT INTO travel_plans (user_id, time_zone, start_date, end_date, policy)
+		 VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (user_id) DO UPDATE SET time_zone = excluded.time_zone,
+		     start_date = excluded.start_date, end_date = excluded.end_date, policy = excluded.policy`,
+		p.UserID, p.TimeZone, p.StartDate.Format(dateLayout), p.EndDate.Format(dateLayout), p.Policy)
+	return err
+}
+
+func (s *sqlStore) DeleteTravelPlan(ctx context.Context, userID string) error {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM travel_plans WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	return requireOneRow(res)
+}
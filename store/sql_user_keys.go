@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pillTickr-backend/crypto"
+)
+
+// userKey returns a user's unwrapped data key, or nil if they have none yet
+func (s *sqlStore) userKey(ctx context.Context, userID string) ([]byte, error) {
+	var wrapped string
+	err := s.q.QueryRowContext(ctx, `SELECT wrapped_key FROM user_keys WHERE user_id = ?`, userID).Scan(&wrapped)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return crypto.UnwrapKey(wrapped)
+}
+
+// ensureUserKey returns a user's data key, creating it on first use
+func (s *sqlStore) ensureUserKey(ctx context.Context, userID string) ([]byte, error) {
+	dek, err := s.userKey(ctx, userID)
+	if err != nil || dek != nil {
+		return dek, err
+	}
+
+	dek, err = crypto.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := crypto.WrapKey(dek)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.q.ExecContext(ctx,
+		`INSERT INTO user_keys (user_id, wrapped_key, created_at) VALUES (?, ?, ?)
+		 ON CONFLICT (user_id) DO NOTHING`,
+		userID, wrapped, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Created concurrently; use the key that won
+		return s.userKey(ctx, userID)
+	}
+	return dek, nil
+}
+
+// userKeyCache loads data keys once per user while working through rows of many users
+type userKeyCache struct {
+	store  *sqlStore
+	create bool
+	keys   map[string][]byte
+}
+
+func (s *sqlStore) newUserKeyCache(create bool) *userKeyCache {
+	return &userKeyCache{store: s, create: create, keys: map[string][]byte{}}
+}
+
+func (c *userKeyCache) get(ctx context.Context, userID string) ([]byte, error) {
+	if dek, ok := c.keys[userID]; ok {
+		return dek, nil
+	}
+	var dek []byte
+	var err error
+	if c.create {
+		dek, err = c.store.ensureUserKey(ctx, userID)
+	} else {
+		dek, err = c.store.userKey(ctx, userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("data key of user %s: %w", userID, err)
+	}
+	c.keys[userID] = dek
+	return dek, nil
+}
+
+func (s *sqlStore) RewrapUserKeys(ctx context.Context, limit int) (int, error) {
+	prefix := crypto.ActiveKeyID() + ":"
+
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT user_id, wrapped_key FROM user_keys
+		 WHERE substr(wrapped_key, 1, ?) <> ?
+		 ORDER BY user_id
+		 LIMIT ?`, len(prefix), prefix, limit)
+	if err != nil {
+		return 0, err
+	}
+	type wrappedKey struct{ userID, wrapped string }
+	var stale []wrappedKey
+	for rows.Next() {
+		var k wrappedKey
+		if err := rows.Scan(&k.userID, &k.wrapped); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stale = append(stale, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, k := range stale {
+		dek, err := crypto.UnwrapKey(k.wrapped)
+		if err != nil {
+			return changed, fmt.Errorf("data key of user %s: %w", k.userID, err)
+		}
+		rewrapped, err := crypto.WrapKey(dek)
+		if err != nil {
+			return changed, err
+		}
+		res, err := s.q.ExecContext(ctx,
+			`UPDATE user_keys SET wrapped_key = ? WHERE user_id = ? AND wrapped_key = ?`,
+			rewrapped, k.userID, k.wrapped)
+		if err != nil {
+			return changed, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			changed++
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"pillTickr-backend/models"
+)
+
+func (s *sqlStore) CreateUser(ctx context.Context, u *models.User) error {
+	return s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		err := tx.q.QueryRowContext(ctx,
+			`INSERT INTO users (name, email, password_hash, time_zone, created_at)
+			 VALUES (?, ?, ?, ?, ?) RETURNING user_id, created_at`,
+			u.Name, u.Email, u.PasswordHash, nullString(u.TimeZone), time.Now().UTC(),
+		).Scan(&u.ID, &u.CreatedAt)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		// Every user gets their own data key from the start
+		_, err = tx.ensureUserKey(ctx, u.ID)
+		return err
+	})
+}
+
+func (s *sqlStore) DeleteUser(ctx context.Context, id string) error {
+	return s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		// Destroying the wrapped data key is what makes copies of the user's
+		// encrypted rows elsewhere, such as backups, unreadable. It is deleted
+		// explicitly rather than left to the cascade so that stays obvious.
+		if _, err := tx.q.ExecContext(ctx, `DELETE FROM user_keys WHERE user_id = ?`, id); err != nil {
+			return err
+		}
+		res, err := tx.q.ExecContext(ctx, `DELETE FROM users WHERE user_id = ?`, id)
+		if err != nil {
+			return err
+		}
+		return requireOneRow(res)
+	})
+}
+
+const userColumns = `user_id, name, email, password_hash, time_zone, created_at`
+
+// scanUser reads a row selected with userColumns
+func scanUser(row interface{ Scan(...any) error }) (models.User, error) {
+	var u models.User
+	var timeZone *string
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &timeZone, &u.CreatedAt)
+	if timeZone != nil {
+		u.TimeZone = *timeZone
+	}
+	return u, notFound(err)
+}
+
+func (s *sqlStore) GetUser(ctx context.Context, id string) (models.User, error) {
+	return scanUser(s.q.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE user_id = ?`, id))
+}
+
+func (s *sqlStore) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	return scanUser(s.q.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, email))
+}
+
+func (s *sqlStore) UpdateUser(ctx context.Context, u models.User) error {
+	res, err := s.q.ExecContext(ctx,
+		`UPDATE users SET name = ?, time_zone = ? WHERE user_id = ?`,
+		u.Name, nullString(u.TimeZone), u.ID)
+	if err != nil {
+		return err
+	}
+	return requireOneRow(res)
+}
+
+func (s *sqlStore) CreateRefreshToken(ctx context.Context, t models.RefreshToken) error {
+	_, err := s.q.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (user_id, family_id, token_hash, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		t.UserID, t.FamilyID, t.TokenHash, t.CreatedAt, t.ExpiresAt)
+	return err
+}
+
+func (s *sqlStore) RotateRefreshToken(ctx context.Context, oldHash string, next models.RefreshToken) (string, error) {
+	var userID string
+	reused := false
+
+	err := s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		var old models.RefreshToken
+		err := tx.q.QueryRowContext(ctx,
+			`SELECT token_id, user_id, family_id, expires_at, used_at, revoked_at
+			 FROM refresh_tokens WHERE token_hash = ?`, oldHash,
+		).Scan(&old.ID, &old.UserID, &old.FamilyID, &old.ExpiresAt, &old.UsedAt, &old.RevokedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRefreshTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+		userID = old.UserID
+
+		if old.UsedAt != nil || old.RevokedAt != nil {
+			// Either the client or an attacker holds a stale copy: end the whole login.
+			// The revocation is committed, the error is reported after the commit.
+			reused = true
+			return tx.revokeFamily(ctx, old.FamilyID, next.CreatedAt)
+		}
+		if next.CreatedAt.After(old.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		// The used_at guard makes concurrent refreshes with the same token race
+		// for it; the loser is treated as a replay
+		res, err := tx.q.ExecContext(ctx,
+			`UPDATE refresh_tokens SET used_at = ? WHERE token_id = ? AND used_at IS NULL`,
+			next.CreatedAt, old.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n != 1 {
+			reused = true
+			return tx.revokeFamily(ctx, old.FamilyID, next.CreatedAt)
+		}
+
+		next.UserID = old.UserID
+		next.FamilyID = old.FamilyID
+		return tx.CreateRefreshToken(ctx, next)
+	})
+	if err != nil {
+		return "", err
+	}
+	if reused {
+		return userID, ErrRefreshTokenReused
+	}
+	return userID, nil
+}
+
+// revokeFamily revokes every live token descended from the same login
+func (s *sqlStore) revokeFamily(ctx context.Context, familyID string, now time.Time) error {
+	_, err := s.q.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`, now, familyID)
+	return err
+}
+
+func (s *sqlStore) RevokeRefreshTokenFamily(ctx context.Context, tokenHash string) error {
+	return s.WithTx(ctx, func(txStore Store) error {
+		tx := txStore.(*sqlStore)
+
+		var familyID string
+		err := tx.q.QueryRowContext(ctx, `SELECT family_id FROM refresh_tokens WHERE token_hash = ?`, tokenHash).Scan(&familyID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.revokeFamily(ctx, familyID, time.Now().UTC())
+	})
+}
+
+func (s *sqlStore) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	_, err := s.q.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, time.Now().UTC(), userID)
+	return err
+}
+
+func (s *sqlStore) DeleteExpiredRefreshTokens(ctx context.Context, userID string, now time.Time) error {
+	_, err := s.q.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = ? AND expires_at < ?`, userID, now)
+	return err
+}
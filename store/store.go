@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"pillTickr-backend/authz"
+	"pillTickr-backend/models"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+
+	ErrRefreshTokenInvalid = errors.New("refresh token invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// Store gives access to every repository. Implementations must make
+// WithTx run fn against stores bound to a single transaction.
+type Store interface {
+	Users() UserStore
+	Medicines() MedicineStore
+	Schedules() ScheduleStore
+	Reminders() ReminderStore
+
+	// OwnerOf resolves the user owning a medicine, schedule, time or reminder
+	OwnerOf(ctx context.Context, resource authz.Resource, id string) (string, error)
+
+	// WithTx runs fn in a transaction, committing if it returns nil. Calling
+	// it on a store that is already transactional just runs fn.
+	WithTx(ctx context.Context, fn func(tx Store) error) error
+}
+
+type UserStore interface {
+	// CreateUser inserts u and fills in its ID and CreatedAt; ErrConflict if the email is taken
+	CreateUser(ctx context.Context, u *models.User) error
+	GetUser(ctx context.Context, id string) (models.User, error)
+	// GetUserByEmail returns the user including the password hash
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	// UpdateUser saves a user's profile: name and time zone
+	UpdateUser(ctx context.Context, u models.User) error
+	// GetTravelPlan returns a user's trip; ErrNotFound if they have none
+	GetTravelPlan(ctx context.Context, userID string) (models.TravelPlan, error)
+	// SetTravelPlan saves p as its user's trip, replacing any earlier one
+	SetTravelPlan(ctx context.Context, p models.TravelPlan) error
+	DeleteTravelPlan(ctx context.Context, userID string) error
+	// DeleteUser removes a user and everything they own, destroying their data
+	// key first so any surviving copies of their encrypted data become unreadable
+	DeleteUser(ctx context.Context, id string) error
+	// RewrapUserKeys re-wraps up to limit data keys still wrapped by a retired
+	// master key with the active one, returning how many changed
+	RewrapUserKeys(ctx context.Context, limit int) (int, error)
+
+	CreateRefreshToken(ctx context.Context, t models.RefreshToken) error
+	// RotateRefreshToken marks the token with oldHash used and stores next in
+	// the same family, returning the owning user. A token that was already
+	// used or revoked revokes its whole family and yields ErrRefreshTokenReused.
+	RotateRefreshToken(ctx context.Context, oldHash string, next models.RefreshToken) (string, error)
+	// RevokeRefreshTokenFamily revokes the family of the token with the given hash, if any
+	RevokeRefreshTokenFamily(ctx context.Context, tokenHash string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+	DeleteExpiredRefreshTokens(ctx context.Context, userID string, now time.Time) error
+}
+
+type MedicineStore interface {
+	// ListMedicines returns a user's medicines, leaving out archived ones unless includeArchived
+	ListMedicines(ctx context.Context, userID string, includeArchived bool) ([]models.Medicine, error)
+	GetMedicine(ctx context.Context, id string) (models.Medicine, error)
+	// CreateMedicine inserts m and fills in its ID and CreatedAt
+	CreateMedicine(ctx context.Context, m *models.Medicine) error
+	// UpdateMedicine saves the details and archived state of m
+	UpdateMedicine(ctx context.Context, m models.Medicine) error
+	// DeleteMedicine removes a medicine with its schedules and reminders
+	DeleteMedicine(ctx context.Context, id string) error
+	IsMedicineArchived(ctx context.Context, id string) (bool, error)
+
+	// ReencryptMedicines encrypts, with the owner's data key, up to limit rows
+	// whose sensitive fields are still plaintext or under a master key, and
+	// returns how many rows changed
+	ReencryptMedicines(ctx context.Context, limit int) (int, error)
+}
+
+type ScheduleStore interface {
+	ListSchedules(ctx context.Context, medicineID string) ([]models.Schedule, error)
+	GetSchedule(ctx context.Context, id string) (models.Schedule, error)
+	// CreateSchedule inserts s and fills in its ID
+	CreateSchedule(ctx context.Context, s *models.Schedule) error
+	// UpdateSchedule saves the definition of s, leaving GeneratedUntil alone
+	UpdateSchedule(ctx context.Context, s models.Schedule) error
+	// DeleteSchedule removes a schedule with its times and reminders
+	DeleteSchedule(ctx context.Context, id string) error
+
+	ListScheduleTimes(ctx context.Context, scheduleID string) ([]models.ScheduleTime, error)
+	// CreateScheduleTime inserts t and fills in its ID
+	CreateScheduleTime(ctx context.Context, t *models.ScheduleTime) error
+	// DeleteScheduleTime removes a time; ErrNotFound unless it belongs to scheduleID
+	DeleteScheduleTime(ctx context.Context, scheduleID, timeID string) error
+	// ReplaceScheduleTimes makes intakeTimes ("HH:MM", no duplicates) the
+	// schedule's whole set of times. Times already present keep their IDs.
+	ReplaceScheduleTimes(ctx context.Context, scheduleID string, intakeTimes []string) ([]models.ScheduleTime, error)
+
+	// ListDoseSteps returns a schedule's dose steps ordered by start date
+	ListDoseSteps(ctx context.Context, scheduleID string) ([]models.DoseStep, error)
+	// ReplaceDoseSteps makes steps the schedule's whole set of dose steps
+	ReplaceDoseSteps(ctx context.Context, scheduleID string, steps []models.DoseStep) ([]models.DoseStep, error)
+
+	// ListScheduleVersions returns a schedule's versions, oldest first
+	ListScheduleVersions(ctx context.Context, scheduleID string) ([]models.ScheduleVersion, error)
+	// CurrentScheduleVersion returns a schedule's latest version; ErrNotFound if it has none
+	CurrentScheduleVersion(ctx context.Context, scheduleID string) (models.ScheduleVersion, error)
+	// RecordScheduleVersion stores the schedule's current definition and
+	// intake times as a new version in effect from at, unless they are
+	// unchanged since the latest version, which is then returned
+	RecordScheduleVersion(ctx context.Context, scheduleID string, at time.Time) (models.ScheduleVersion, error)
+
+	// ListPauses returns a schedule's pause periods, oldest first
+	ListPauses(ctx context.Context, scheduleID string) ([]models.SchedulePause, error)
+	// PauseSchedule puts a schedule on hold from at until resumeAt, or until
+	// resumed when nil; ErrConflict if it is already paused then
+	PauseSchedule(ctx context.Context, scheduleID string, at time.Time, resumeAt *time.Time) (models.SchedulePause, error)
+	// ResumeSchedule ends the pause in effect at at; ErrConflict if there is none
+	ResumeSchedule(ctx context.Context, scheduleID string, at time.Time) (models.SchedulePause, error)
+
+	// ListSchedulesToGenerate returns schedules of active medicines still
+	// running on today whose reminders are not materialized up to horizon
+	ListSchedulesToGenerate(ctx context.Context, today, horizon time.Time) ([]string, error)
+	SetGeneratedUntil(ctx context.Context, scheduleID string, until time.Time) error
+}
+
+// ReminderHistory is a reminder together with the medicine it belongs to
+type ReminderHistory struct {
+	MedicineID   string
+	MedicineName string
+	Reminder     models.Reminder
+}
+
+// OverdueReminder is a pending reminder past its due time, with its schedule's grace window
+type OverdueReminder struct {
+	ID           string
+	Due          time.Time
+	GraceMinutes int
+}
+
+// DueReminder is a reminder ready to be notified, with who and what it is for
+type DueReminder struct {
+	Reminder models.Reminder
+	Medicine models.Medicine
+	User     models.User
+}
+
+type ReminderStore interface {
+	// ListReminders returns a user's reminders with their outcome filled in
+	ListReminders(ctx context.Context, userID string) ([]models.Reminder, error)
+	GetReminder(ctx context.Context, id string) (models.Reminder, error)
+	// LastTakenAt returns when the latest dose of a schedule was taken, nil if none was
+	LastTakenAt(ctx context.Context, scheduleID string) (*time.Time, error)
+	// CountPlannedDoses counts a schedule's reminders that are taken or still
+	// pending, i.e. the doses of a fixed course already accounted for
+	CountPlannedDoses(ctx context.Context, scheduleID string) (int, error)
+	// ListReminderHistory returns a user's reminders due in [from, to) with
+	// outcomes, leaving out doses not taken while their schedule was paused
+	ListReminderHistory(ctx context.Context, userID string, from, to time.Time) ([]ReminderHistory, error)
+	// CreateReminder inserts r and fills in its ID
+	CreateReminder(ctx context.Context, r *models.Reminder) error
+	// UpdateReminder saves r; ErrConflict if it is generated and another
+	// generated reminder of its schedule is due at the new time
+	UpdateReminder(ctx context.Context, r models.Reminder) error
+	DeleteReminder(ctx context.Context, id string) error
+
+	// InsertGeneratedReminder stores a generated reminder unless another
+	// generated one exists at the same instant, reporting whether it was inserted
+	InsertGeneratedReminder(ctx context.Context, r models.Reminder) (bool, error)
+	// DeleteFutureGeneratedReminders removes a schedule's generated reminders
+	// that are still pending and due at or after from
+	DeleteFutureGeneratedReminders(ctx context.Context, scheduleID string, from time.Time) error
+	// DeleteFutureMedicineReminders removes every pending reminder of a
+	// medicine due at or after from, generated or manual
+	DeleteFutureMedicineReminders(ctx context.Context, medicineID string, from time.Time) error
+
+	ListOverdueReminders(ctx context.Context, now time.Time) ([]OverdueReminder, error)
+	// MarkMissed flips a reminder to missed if it is still pending
+	MarkMissed(ctx context.Context, id string) (bool, error)
+
+	// ListDueForNotification returns pending, undelivered reminders due
+	// between since and now that are not leased, backing off or paused
+	ListDueForNotification(ctx context.Context, since, now time.Time, maxAttempts, limit int) ([]DueReminder, error)
+	// ClaimForNotification leases a reminder until leaseUntil and counts the
+	// attempt, returning the attempt count; ok is false if someone else holds it
+	ClaimForNotification(ctx context.Context, id string, now, leaseUntil time.Time) (attempts int, ok bool, err error)
+	// RecordNotificationAttempt logs an attempt; on success the reminder is
+	// marked notified, otherwise it is retried at retryAt
+	RecordNotificationAttempt(ctx context.Context, id, notifier string, at time.Time, sendErr error, retryAt time.Time) error
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnvDuration reads a duration such as "90m" from the environment, falling back on missing or bad values
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		slog.Warn("Invalid duration in environment, using default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return d
+}
+
+// GetEnvInt reads a positive integer from the environment, falling back on missing or bad values
+func GetEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		slog.Warn("Invalid integer in environment, using default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return n
+}
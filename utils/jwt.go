@@ -4,31 +4,44 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func GetUserID(c *gin.Context) (float64, bool) {
+// GetUserID returns the authenticated user's ID from the JWT claims. Tokens
+// carry it as a string, but a numeric claim is accepted as well.
+func GetUserID(c *gin.Context) (string, bool) {
 	claims, exists := c.Get("user")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User claims not found"})
-		return 0, false
+		return "", false
 	}
 
 	mapClaims, ok := claims.(jwt.MapClaims)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user claims"})
-		return 0, false
+		return "", false
 	}
 
 	userID, ok := mapClaims["id"]
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
-		return 0, false
+		return "", false
 	}
-	return userID.(float64), true
+
+	switch id := userID.(type) {
+	case string:
+		if _, err := strconv.ParseInt(id, 10, 64); err == nil {
+			return id, true
+		}
+	case float64:
+		return strconv.FormatInt(int64(id), 10), true
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+	return "", false
 }
 
 func GenerateJWT(userID string, email string, duration time.Duration) (string, int64, error) {